@@ -0,0 +1,54 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// providerHosts maps the hostnames of the self-hosted git providers to the providers, such as: gitlab.example.com
+var providerHosts = map[string]Provider{}
+var providerHostsLock = sync.RWMutex{}
+
+// SetProviderHosts sets the hostnames of the self-hosted git providers which are used to detect the default provider
+func SetProviderHosts(hosts map[string]Provider) (err error) {
+	newHosts := make(map[string]Provider, len(hosts))
+	for host, provider := range hosts {
+		if !provider.IsValid() {
+			err = fmt.Errorf("invalid provider %s of host %s", provider, host)
+			return
+		}
+		newHosts[strings.ToLower(host)] = provider
+	}
+
+	providerHostsLock.Lock()
+	defer providerHostsLock.Unlock()
+	providerHosts = newHosts
+	return
+}
+
+// getProviderByHost returns the provider of the git address by the self-hosted hostnames
+func getProviderByHost(address string) (provider Provider, ok bool) {
+	host := GetHost(address)
+	if host == "" {
+		return
+	}
+
+	providerHostsLock.RLock()
+	defer providerHostsLock.RUnlock()
+	provider, ok = providerHosts[strings.ToLower(host)]
+	return
+}
+
+// GetHost returns the host (without port) of a git address, such as: https://github.com/a/b, git@github.com:a/b
+func GetHost(address string) (host string) {
+	if gitURL, err := url.Parse(address); err == nil && gitURL.Host != "" {
+		host = gitURL.Hostname()
+	} else if at := strings.Index(address, "@"); at >= 0 {
+		if colon := strings.Index(address[at:], ":"); colon > 0 {
+			host = address[at+1 : at+colon]
+		}
+	}
+	return
+}
@@ -15,3 +15,35 @@ func TestGitProvider(t *testing.T) {
 	assert.Equal(t, ProviderGitHub, GetDefaultProvider(&Repository{Provider: ProviderGitHub}))
 	assert.Equal(t, Provider(""), GetDefaultProvider(&Repository{Address: ""}))
 }
+
+func TestAction(t *testing.T) {
+	assert.True(t, ActionAuto.IsValid())
+	assert.True(t, ActionRelease.IsValid())
+	assert.False(t, Action("fake").IsValid())
+}
+
+func TestProviderHosts(t *testing.T) {
+	defer func() {
+		_ = SetProviderHosts(nil)
+	}()
+
+	err := SetProviderHosts(map[string]Provider{"fake.com": "fake"})
+	assert.NotNil(t, err)
+
+	err = SetProviderHosts(map[string]Provider{"GitLab.example.com": ProviderGitlab, "github.example.com": ProviderGitHub})
+	assert.Nil(t, err)
+	assert.Equal(t, ProviderGitlab, GetDefaultProvider(&Repository{Address: "https://gitlab.example.com/x/b"}))
+	assert.Equal(t, ProviderGitlab, GetDefaultProvider(&Repository{Address: "git@gitlab.example.com:x/b.git"}))
+	assert.Equal(t, ProviderGitHub, GetDefaultProvider(&Repository{Address: "https://github.example.com:8443/x/b"}))
+	assert.Equal(t, ProviderUnknown, GetDefaultProvider(&Repository{Address: "https://git.example.com/x/b"}))
+	// the explicit provider takes precedence
+	assert.Equal(t, ProviderGitea, GetDefaultProvider(&Repository{
+		Address: "https://gitlab.example.com/x/b", Provider: ProviderGitea}))
+}
+
+func TestGetHost(t *testing.T) {
+	assert.Equal(t, "github.com", GetHost("https://github.com/x/b"))
+	assert.Equal(t, "git.example.com", GetHost("http://git.example.com:3000/x/b"))
+	assert.Equal(t, "github.com", GetHost("git@github.com:x/b.git"))
+	assert.Equal(t, "", GetHost("/tmp/b"))
+}
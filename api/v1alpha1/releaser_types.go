@@ -17,9 +17,11 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"strings"
+	"time"
 )
 
 // EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -36,8 +38,118 @@ type ReleaserSpec struct {
 	Repositories []Repository       `json:"repositories,omitempty"`
 	GitOps       *GitOps            `json:"gitOps,omitempty"`
 	Secret       v1.SecretReference `json:"secret,omitempty"`
+	// Concurrency is the number of repositories which can be released at the same time
+	// +optional
+	Concurrency int `json:"concurrency,omitempty"`
+	// DryRun indicates to make a release plan instead of releasing it, nothing will be pushed
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+	// CreateBranch indicates to cut a release branch, such as release-1.2, before tagging each repository
+	// +optional
+	CreateBranch bool `json:"createBranch,omitempty"`
+	// +optional
+	ReleaseNotes *ReleaseNotes `json:"releaseNotes,omitempty"`
+	// +optional
+	NextVersion *NextVersion `json:"nextVersion,omitempty"`
+	// +optional
+	Versioning *Versioning `json:"versioning,omitempty"`
+	// +optional
+	Signing *Signing `json:"signing,omitempty"`
+	// Author is the identity of the commits and tags, it has a higher priority than the keys name and email of
+	// the secret. The noreply email of the git provider is the default one.
+	// +optional
+	Author *Author `json:"author,omitempty"`
+}
+
+// Author is the identity of a git user
+type Author struct {
+	// +optional
+	Name string `json:"name,omitempty"`
+	// +optional
+	Email string `json:"email,omitempty"`
+}
+
+// Signing is the OpenPGP key which signs the tags and commits
+type Signing struct {
+	// Secret contains the armored private key (signing-key), and the optional keys passphrase, name and email.
+	// The namespace of the Releaser is the default one.
+	Secret v1.SecretReference `json:"secret"`
+}
+
+// Versioning is the versioning scheme of a Releaser
+type Versioning struct {
+	// Scheme is the name of the versioning scheme, semver is the default one
+	// +kubebuilder:validation:Enum=semver;calver
+	// +optional
+	Scheme string `json:"scheme,omitempty"`
+	// Format is the format of calver, such as: YYYY.0M.MICRO (default), YY.0M.MICRO
+	// +optional
+	Format string `json:"format,omitempty"`
+}
+
+// GetVersioningScheme returns the versioning scheme of the Releaser, CalVer takes the current date from the clock
+func (s *ReleaserSpec) GetVersioningScheme(now func() time.Time) (scheme versioning.Scheme, err error) {
+	if s.Versioning == nil {
+		scheme = versioning.NewSemVer()
+		return
+	}
+	scheme, err = versioning.NewSchemeWithClock(s.Versioning.Scheme, s.Versioning.Format, now)
+	return
+}
+
+// NextVersion indicates how to decide the versions of a draft Releaser
+type NextVersion struct {
+	// Strategy is the way to bump the versions of the next draft Releaser after a release. By default, it bumps
+	// the pre-release number or the patch number, and prepares an extra draft without the pre-release if necessary.
+	// +optional
+	Strategy BumpStrategy `json:"strategy,omitempty"`
+	// PreReleaseIdentifier is the identifier of the pre-release strategy, such as: alpha, rc
+	// +optional
+	PreReleaseIdentifier string `json:"preReleaseIdentifier,omitempty"`
+	// InferFromCommits indicates to infer the versions of a draft Releaser from the conventional commits
+	// since the last tag of each repository. A breaking change bumps the major number, a feature bumps the minor
+	// number, others bump the patch number.
+	// +optional
+	InferFromCommits bool `json:"inferFromCommits,omitempty"`
+}
+
+// ReleaseNotes indicates how to generate the release notes from the commits between the previous tag and the new one
+type ReleaseNotes struct {
+	// ChangelogFile is the file which the release notes will be written into before tagging, such as: CHANGELOG.md.
+	// It is only available when releasing the head of the branch.
+	// +optional
+	ChangelogFile string `json:"changelogFile,omitempty"`
+	// Template is the Go text/template of the release notes of each repository.
+	// The available fields are: .Version, .PreviousVersion, .Repository, .Commits, .Authors, .PullRequests, .Groups
+	// and .BreakingChanges.
+	// +optional
+	Template string `json:"template,omitempty"`
+	// TemplateRef refers to a key of a ConfigMap which holds the template, the ConfigMap must be in the same namespace.
+	// It is ignored if the Template is not empty.
+	// +optional
+	TemplateRef *v1.ConfigMapKeySelector `json:"templateRef,omitempty"`
 }
 
+// BumpStrategy is the strategy of bumping versions
+// +kubebuilder:validation:Enum=patch;minor;major;prerelease;none;calver
+type BumpStrategy string
+
+const (
+	// BumpStrategyPatch bumps the patch number, or drops the pre-release
+	BumpStrategyPatch BumpStrategy = "patch"
+	// BumpStrategyMinor bumps the minor number, or drops the pre-release of a minor version
+	BumpStrategyMinor BumpStrategy = "minor"
+	// BumpStrategyMajor bumps the major number, or drops the pre-release of a major version
+	BumpStrategyMajor BumpStrategy = "major"
+	// BumpStrategyPreRelease bumps the pre-release number with the identifier
+	BumpStrategyPreRelease BumpStrategy = "prerelease"
+	// BumpStrategyNone does not create the next draft Releaser
+	BumpStrategyNone BumpStrategy = "none"
+	// BumpStrategyCalVer switches the semantic versions to the calendar versioning in the default format, such as:
+	// v1.2.3 -> v2021.09.0, the next draft Releaser has the calver scheme. It is the default strategy of calver.
+	BumpStrategyCalVer BumpStrategy = "calver"
+)
+
 // Phase is the stage of release request
 type Phase string
 
@@ -69,12 +181,42 @@ type Repository struct {
 	Address  string   `json:"address"`
 	// +optional
 	Branch string `json:"branch,omitempty"`
+	// Commit is the SHA (or a reference) which the tag will be created on, it must be reachable from the branch.
+	// The head of the branch will be tagged if it is empty.
+	// +optional
+	Commit string `json:"commit,omitempty"`
 	// +optional
 	Version string `json:"version,omitempty"`
 	// +optional
 	Message string `json:"message,omitempty"`
 	// +optional
 	Action Action `json:"action,omitempty"`
+	// DependsOn are the names of the repositories which need to be released before this one
+	// +optional
+	DependsOn []string `json:"dependsOn,omitempty"`
+	// OnExistingTag is the policy when the tag exists already, skip is the default one
+	// +optional
+	OnExistingTag OnExistingTag `json:"onExistingTag,omitempty"`
+	// VersionFiles are the files which contain the version, they will be updated and committed before tagging.
+	// They are not allowed with a pinned commit, only the head of the branch can be released.
+	// +optional
+	VersionFiles []VersionFile `json:"versionFiles,omitempty"`
+}
+
+// VersionFile is a file which contains the version, such as: package.json, Chart.yaml, version.go
+type VersionFile struct {
+	// Path is the file path relative to the root of the repository
+	Path string `json:"path"`
+	// Pattern is a regular expression, the first group (or the whole match if there is no group) of the matches
+	// will be replaced by the version
+	// +optional
+	Pattern string `json:"pattern,omitempty"`
+	// Field is the path of a YAML (or JSON) field, such as: version, image.tag
+	// +optional
+	Field string `json:"field,omitempty"`
+	// TrimPrefix indicates to remove the prefix v of the version, such as: 1.2.0 instead of v1.2.0
+	// +optional
+	TrimPrefix bool `json:"trimPrefix,omitempty"`
 }
 
 // GetDefaultProvider returns the default git provider
@@ -94,6 +236,8 @@ func GetDefaultProvider(r *Repository) Provider {
 		return ProviderGitee
 	} else if strings.HasPrefix(address, "https://gitea.com/") {
 		return ProviderGitea
+	} else if provider, ok := getProviderByHost(address); ok {
+		return provider
 	} else if address != "" {
 		return ProviderUnknown
 	}
@@ -105,8 +249,74 @@ type GitOps struct {
 	Enable     bool               `json:"enable,omitempty"`
 	Repository Repository         `json:"repository,omitempty"`
 	Secret     v1.SecretReference `json:"secret,omitempty"`
+	// Mode is the way to submit the changes of the Releasers, push is the default one
+	// +kubebuilder:validation:Enum=push;pr
+	// +optional
+	Mode GitOpsMode `json:"mode,omitempty"`
+	// AutoMerge indicates to merge the pull request once all the commit statuses succeeded, it is only available
+	// in the pr mode
+	// +optional
+	AutoMerge bool `json:"autoMerge,omitempty"`
+	// Updates are the files which will be updated with the released version, in the same commit as the Releaser
+	// +optional
+	Updates []GitOpsUpdate `json:"updates,omitempty"`
+}
+
+// GitOpsUpdate describes a file in the GitOps repository which will be updated with the released version
+type GitOpsUpdate struct {
+	// Path is the file path relative to the root of the GitOps repository
+	Path string `json:"path"`
+	// Kind is the kind of the file
+	// +kubebuilder:validation:Enum=kustomization;helmChart;helmValues;yaml
+	Kind GitOpsUpdateKind `json:"kind"`
+	// Image is the name of the image in the kustomization, it is required by the kustomization kind
+	// +optional
+	Image string `json:"image,omitempty"`
+	// Fields are the YAML paths to be updated, such as: image.tag, spec.template.spec.containers[0].env[0].value.
+	// The whole value of each field is replaced by the version. They are required by the yaml kind. The defaults of the helmChart kind are version and appVersion,
+	// the default of the helmValues kind is image.tag.
+	// +optional
+	Fields []string `json:"fields,omitempty"`
+	// Repository is the name of the repository whose version will be used, the version of the Releaser is the
+	// default one
+	// +optional
+	Repository string `json:"repository,omitempty"`
+}
+
+// GitOpsUpdateKind is the kind of a file in the GitOps repository
+type GitOpsUpdateKind string
+
+const (
+	// GitOpsUpdateKustomization updates the newTag of the images in a kustomization.yaml
+	GitOpsUpdateKustomization GitOpsUpdateKind = "kustomization"
+	// GitOpsUpdateHelmChart updates the version and appVersion of a Helm Chart.yaml
+	GitOpsUpdateHelmChart GitOpsUpdateKind = "helmChart"
+	// GitOpsUpdateHelmValues updates the fields of a Helm values.yaml
+	GitOpsUpdateHelmValues GitOpsUpdateKind = "helmValues"
+	// GitOpsUpdateYAML updates the fields of a generic YAML file
+	GitOpsUpdateYAML GitOpsUpdateKind = "yaml"
+)
+
+// IsValid checks if this is valid
+func (k GitOpsUpdateKind) IsValid() bool {
+	switch k {
+	case GitOpsUpdateKustomization, GitOpsUpdateHelmChart, GitOpsUpdateHelmValues, GitOpsUpdateYAML:
+		return true
+	default:
+		return false
+	}
 }
 
+// GitOpsMode is the way to submit the changes into the GitOps repository
+type GitOpsMode string
+
+const (
+	// GitOpsModePush pushes the changes to the branch directly
+	GitOpsModePush GitOpsMode = "push"
+	// GitOpsModePullRequest pushes the changes to a generated branch, then opens a pull request
+	GitOpsModePullRequest GitOpsMode = "pr"
+)
+
 // Provider represents a git provider, such as: GitHub, Gitlab
 type Provider string
 
@@ -119,6 +329,50 @@ const (
 	ProviderUnknown   Provider = "unknown"
 )
 
+// IsValid checks if this is valid
+func (p Provider) IsValid() bool {
+	switch p {
+	case ProviderGitHub, ProviderGitlab, ProviderBitbucket, ProviderGitee, ProviderGitea:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsRelease checks if the provider is able to create releases, it needs to be consistent with internal_scm
+func (p Provider) SupportsRelease() bool {
+	switch p {
+	case ProviderGitHub, ProviderGitlab, ProviderGitea, ProviderGitee, ProviderBitbucket:
+		return true
+	default:
+		return false
+	}
+}
+
+// SupportsPullRequest checks if the provider is able to open pull requests, it needs to be consistent with
+// internal_scm
+func (p Provider) SupportsPullRequest() bool {
+	switch p {
+	case ProviderGitHub, ProviderGitlab, ProviderGitea, ProviderGitee, ProviderBitbucket:
+		return true
+	default:
+		return false
+	}
+}
+
+// OnExistingTag is the policy when the tag exists already
+// +kubebuilder:validation:Enum=skip;fail;verify
+type OnExistingTag string
+
+const (
+	// OnExistingTagSkip skips tagging the repository
+	OnExistingTagSkip OnExistingTag = "skip"
+	// OnExistingTagFail fails the release
+	OnExistingTagFail OnExistingTag = "fail"
+	// OnExistingTagVerify succeeds only if the existing tag points to the commit which would be tagged
+	OnExistingTagVerify OnExistingTag = "verify"
+)
+
 // Action indicates the action once the request phase to be ready
 type Action string
 
@@ -129,13 +383,159 @@ const (
 	ActionRelease    Action = "release"
 )
 
+// IsValid checks if this is valid
+func (a Action) IsValid() bool {
+	switch a {
+	case ActionAuto, ActionTag, ActionPreRelease, ActionRelease:
+		return true
+	default:
+		return false
+	}
+}
+
 // ReleaserStatus defines the observed state of Releaser
 type ReleaserStatus struct {
 	// +optional
 	StartTime *metav1.Time `json:"startTime,omitempty"`
 	// +optional
 	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
-	Conditions     []Condition  `json:"conditions,omitempty"`
+	// Attempts is the number of times the current spec was tried to release
+	// +optional
+	Attempts int `json:"attempts,omitempty"`
+	// LastAttemptTime is the start time of the latest attempt, a failed release is retried with a backoff since it
+	// +optional
+	LastAttemptTime *metav1.Time `json:"lastAttemptTime,omitempty"`
+	Conditions      []Condition  `json:"conditions,omitempty"`
+	// Repositories holds the release status of each git repository
+	// +optional
+	Repositories []RepositoryStatus `json:"repositories,omitempty"`
+	// Plan describes what would happen once the Releaser is ready, it only exists in the dry-run mode
+	// +optional
+	Plan *ReleasePlan `json:"plan,omitempty"`
+	// PullRequest is the pull request of the GitOps repository, it only exists in the pr mode
+	// +optional
+	PullRequest *PullRequestStatus `json:"pullRequest,omitempty"`
+	// Issue is the issue of the GitOps repository which reports the errors, it only exists once an issue was opened
+	// +optional
+	Issue *IssueStatus `json:"issue,omitempty"`
+}
+
+// IssueStatus is the status of an issue
+type IssueStatus struct {
+	// Number is a string because some git providers (such as Gitee) have letters in it
+	// +optional
+	Number string `json:"number,omitempty"`
+	Title  string `json:"title"`
+	// +optional
+	Closed bool `json:"closed,omitempty"`
+}
+
+// PullRequestStatus is the status of a pull request
+type PullRequestStatus struct {
+	Number int    `json:"number"`
+	Link   string `json:"link,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	// +optional
+	Merged bool `json:"merged,omitempty"`
+}
+
+// ReleasePlan describes what would happen once the Releaser is ready
+type ReleasePlan struct {
+	// ObservedGeneration is the generation of the Releaser which this plan was made for
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// +optional
+	PlanTime *metav1.Time `json:"planTime,omitempty"`
+	// NextReleasers are the names of the draft Releasers which would be created after releasing
+	// +optional
+	NextReleasers []string `json:"nextReleasers,omitempty"`
+	// +optional
+	Repositories []RepositoryPlan `json:"repositories,omitempty"`
+}
+
+// RepositoryPlan describes what would happen to a git repository
+type RepositoryPlan struct {
+	// +optional
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+	// +optional
+	Branch string `json:"branch,omitempty"`
+	// +optional
+	Version string `json:"version,omitempty"`
+	// +optional
+	Action Action `json:"action,omitempty"`
+	// Commit is the SHA of the commit which would be tagged
+	// +optional
+	Commit string `json:"commit,omitempty"`
+	// ReleaseBranch is the name of the release branch which would be created
+	// +optional
+	ReleaseBranch string `json:"releaseBranch,omitempty"`
+	// TagExists indicates that the tag exists already
+	// +optional
+	TagExists bool `json:"tagExists,omitempty"`
+	// NextVersion is the version of this repository in the next draft Releaser
+	// +optional
+	NextVersion string `json:"nextVersion,omitempty"`
+	// Stage is the order of releasing this repository, it starts from zero
+	// +optional
+	Stage int `json:"stage,omitempty"`
+	// VersionFiles are the paths of the version files which would be updated and committed before tagging
+	// +optional
+	VersionFiles []string `json:"versionFiles,omitempty"`
+	// Error is the problem found during the planning
+	// +optional
+	Error string `json:"error,omitempty"`
+}
+
+// RepositoryStatus indicates the release status of a git repository
+type RepositoryStatus struct {
+	// +optional
+	Name    string `json:"name,omitempty"`
+	Address string `json:"address"`
+	// +optional
+	Version string `json:"version,omitempty"`
+	// +optional
+	Action Action `json:"action,omitempty"`
+	// +optional
+	Status ConditionStatus `json:"status,omitempty"`
+	// Commit is the SHA of the commit which the pushed tag points to
+	// +optional
+	Commit string `json:"commit,omitempty"`
+	// ReleaseBranch is the name of the release branch
+	// +optional
+	ReleaseBranch string `json:"releaseBranch,omitempty"`
+	// ReleaseURL is the address of the release created on the git provider
+	// +optional
+	ReleaseURL string `json:"releaseURL,omitempty"`
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Attempts is the number of times this repository was tried to release
+	// +optional
+	Attempts int `json:"attempts,omitempty"`
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// GetRepositoryStatus returns the status of the given repository, returns nil if it does not exist
+func (s *ReleaserStatus) GetRepositoryStatus(repo Repository) *RepositoryStatus {
+	for i := range s.Repositories {
+		status := &s.Repositories[i]
+		if status.Name == repo.Name && status.Address == repo.Address {
+			return status
+		}
+	}
+	return nil
+}
+
+// SetRepositoryStatus adds or replaces the status of a repository
+func (s *ReleaserStatus) SetRepositoryStatus(status RepositoryStatus) {
+	if existing := s.GetRepositoryStatus(Repository{Name: status.Name, Address: status.Address}); existing != nil {
+		*existing = status
+		return
+	}
+	s.Repositories = append(s.Repositories, status)
 }
 
 // Condition indicates the status of each git repositories
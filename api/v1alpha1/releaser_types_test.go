@@ -11,3 +11,19 @@ func TestPhase(t *testing.T) {
 	assert.True(t, Phase("ready").IsValid())
 	assert.True(t, Phase("done").IsValid())
 }
+
+func TestRepositoryStatus(t *testing.T) {
+	status := &ReleaserStatus{}
+	repo := Repository{Name: "test", Address: "https://github.com/x/b"}
+	assert.Nil(t, status.GetRepositoryStatus(repo))
+
+	status.SetRepositoryStatus(RepositoryStatus{Name: "test", Address: "https://github.com/x/b", Attempts: 1})
+	status.SetRepositoryStatus(RepositoryStatus{Name: "other", Address: "https://github.com/x/c", Attempts: 1})
+	assert.Equal(t, 2, len(status.Repositories))
+	assert.Equal(t, 1, status.GetRepositoryStatus(repo).Attempts)
+
+	// replace the existing one
+	status.SetRepositoryStatus(RepositoryStatus{Name: "test", Address: "https://github.com/x/b", Attempts: 2})
+	assert.Equal(t, 2, len(status.Repositories))
+	assert.Equal(t, 2, status.GetRepositoryStatus(repo).Attempts)
+}
@@ -17,18 +17,39 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"context"
 	"errors"
-	"k8s.io/apimachinery/pkg/runtime"
+	"fmt"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
+	"golang.org/x/crypto/ssh"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"net/http"
+	"net/url"
+	"path"
 	"reflect"
+	"regexp"
+	"strings"
+	"time"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
 // log is for logging in this package.
 var releaserlog = logf.Log.WithName("releaser-resource")
 
+// validatingWebhookPath is the path of the validating webhook, it must be the same as the marker below
+const validatingWebhookPath = "/validate-devops-kubesphere-io-v1alpha1-releaser"
+
 func (r *Releaser) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	// the validator is registered by hand because it needs the client to check the referenced resources
+	mgr.GetWebhookServer().Register(validatingWebhookPath, &webhook.Admission{
+		Handler: &releaserValidator{client: mgr.GetAPIReader()},
+	})
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(r).
 		Complete()
@@ -85,33 +106,335 @@ func (r *Releaser) Default() {
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
 //+kubebuilder:webhook:path=/validate-devops-kubesphere-io-v1alpha1-releaser,mutating=false,failurePolicy=fail,sideEffects=None,groups=devops.kubesphere.io,resources=releasers,verbs=create;update,versions=v1alpha1,name=vreleaser.kb.io,admissionReviewVersions={v1,v1beta1}
 
-var _ webhook.Validator = &Releaser{}
+// releaserValidator validates the Releasers, the client is used to check the referenced resources
+type releaserValidator struct {
+	client  client.Reader
+	decoder *admission.Decoder
+}
+
+var _ admission.Handler = &releaserValidator{}
+var _ admission.DecoderInjector = &releaserValidator{}
+
+// InjectDecoder implements admission.DecoderInjector
+func (v *releaserValidator) InjectDecoder(decoder *admission.Decoder) error {
+	v.decoder = decoder
+	return nil
+}
+
+// Handle implements admission.Handler, it validates the Releaser of a create or update request
+func (v *releaserValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return admission.Allowed("")
+	}
+
+	releaser := &Releaser{}
+	if err := v.decoder.DecodeRaw(req.Object, releaser); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
 
-// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
-func (r *Releaser) ValidateCreate() error {
+	var err error
+	if req.Operation == admissionv1.Create {
+		err = v.ValidateCreate(ctx, releaser)
+	} else {
+		oldReleaser := &Releaser{}
+		if err = v.decoder.DecodeRaw(req.OldObject, oldReleaser); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		err = v.ValidateUpdate(ctx, oldReleaser, releaser)
+	}
+	if err != nil {
+		return admission.Denied(err.Error())
+	}
+	return admission.Allowed("")
+}
+
+// ValidateCreate checks the spec and the referenced secrets of a new Releaser
+func (v *releaserValidator) ValidateCreate(ctx context.Context, r *Releaser) error {
 	releaserlog.Info("validate create", "name", r.Name)
 
-	if !r.Spec.Phase.IsValid() {
-		return errors.New("invalid phase")
+	if err := r.Spec.validate(); err != nil {
+		return err
 	}
-	return nil
+	return v.validateSecrets(ctx, r)
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
-func (r *Releaser) ValidateUpdate(old runtime.Object) error {
+// ValidateUpdate checks the spec and the referenced secrets of a Releaser if the spec is changed
+func (v *releaserValidator) ValidateUpdate(ctx context.Context, oldReleaser, r *Releaser) error {
 	releaserlog.Info("validate update", "name", r.Name)
 
-	oldReleaser := old.(*Releaser)
-	if oldReleaser.Spec.Phase == PhaseDone && !reflect.DeepEqual(oldReleaser.Spec, r.Spec) {
+	if reflect.DeepEqual(oldReleaser.Spec, r.Spec) {
+		// the metadata updates, such as the annotations from the controller, are always allowed
+		return nil
+	}
+	if oldReleaser.Spec.Phase == PhaseDone {
 		return errors.New("not allow to manipulate this release any more once the phase is done")
 	}
+	if err := r.Spec.validate(); err != nil {
+		return err
+	}
+	return v.validateSecrets(ctx, r)
+}
+
+// validate checks the spec before releasing it, so that the mistakes will not show up after the release partly ran
+func (s *ReleaserSpec) validate() (err error) {
+	if !s.Phase.IsValid() {
+		return errors.New("invalid phase")
+	}
+	if _, err = s.GetReleaseStages(); err != nil {
+		return
+	}
+	if err = s.validateVersions(); err != nil {
+		return
+	}
+	if err = s.validateRepositories(); err != nil {
+		return
+	}
+
+	if s.GitOps != nil && s.GitOps.Enable {
+		if s.GitOps.Repository.Address == "" {
+			return errors.New("the repository address is required when GitOps is enabled")
+		}
+		if err = validateAddress(s.GitOps.Repository.Address); err != nil {
+			return fmt.Errorf("invalid GitOps repository: %v", err)
+		}
+		if provider := GetDefaultProvider(&s.GitOps.Repository); s.GitOps.Mode == GitOpsModePullRequest &&
+			!provider.SupportsPullRequest() {
+			return fmt.Errorf("provider %s of the GitOps repository does not support pull requests", provider)
+		}
+		if err = s.validateGitOpsUpdates(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// validateGitOpsUpdates checks the paths, kinds and the referenced repositories of the GitOps updates
+func (s *ReleaserSpec) validateGitOpsUpdates() (err error) {
+	for _, update := range s.GitOps.Updates {
+		if !isRelativePath(update.Path) {
+			return fmt.Errorf("invalid GitOps update path: %q, it must be relative to the repository", update.Path)
+		}
+		if !update.Kind.IsValid() {
+			return fmt.Errorf("invalid kind %s of GitOps update %s", update.Kind, update.Path)
+		}
+		if update.Kind == GitOpsUpdateYAML && len(update.Fields) == 0 {
+			return fmt.Errorf("fields are required by GitOps update %s", update.Path)
+		}
+		if update.Kind == GitOpsUpdateKustomization && update.Image == "" {
+			return fmt.Errorf("image is required by GitOps update %s", update.Path)
+		}
+		if update.Repository != "" && !s.hasRepository(update.Repository) {
+			return fmt.Errorf("cannot find repository %s of GitOps update %s", update.Repository, update.Path)
+		}
+	}
+	return
+}
+
+func (s *ReleaserSpec) hasRepository(name string) bool {
+	for _, repo := range s.Repositories {
+		if repo.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// validateRepositories checks the addresses, actions and providers of the repositories, and the duplicated ones
+func (s *ReleaserSpec) validateRepositories() (err error) {
+	scheme, _ := s.GetVersioningScheme(time.Now)
+	names := map[string]bool{}
+	addresses := map[string]bool{}
+	for i := range s.Repositories {
+		repo := &s.Repositories[i]
+		if err = validateAddress(repo.Address); err != nil {
+			return
+		}
+
+		if repo.Name != "" {
+			if names[repo.Name] {
+				return fmt.Errorf("duplicated repository name: %s", repo.Name)
+			}
+			names[repo.Name] = true
+		}
+		address := normalizeAddress(repo.Address)
+		if addresses[address] {
+			return fmt.Errorf("duplicated repository address: %s", repo.Address)
+		}
+		addresses[address] = true
+
+		if err = validateVersionFiles(repo); err != nil {
+			return
+		}
+
+		if repo.Action != "" && !repo.Action.IsValid() {
+			return fmt.Errorf("invalid action %s of repository %s", repo.Action, repo.Address)
+		}
+		action := repo.Action
+		if action == ActionAuto && scheme != nil && scheme.IsPreRelease(repo.Version) {
+			action = ActionPreRelease
+		}
+		needRelease := action == ActionRelease || action == ActionPreRelease
+		if provider := GetDefaultProvider(repo); needRelease && !provider.SupportsRelease() {
+			return fmt.Errorf("provider %s of repository %s does not support action %s", provider, repo.Address, action)
+		}
+	}
+	return
+}
+
+// validateVersionFiles checks the paths of the version files, and one of the pattern and field is required.
+// The version files cannot be committed on top of a pinned commit.
+func validateVersionFiles(repo *Repository) error {
+	if len(repo.VersionFiles) > 0 && repo.Commit != "" {
+		return fmt.Errorf("version files of repository %s are not allowed with the pinned commit %s",
+			repo.Address, repo.Commit)
+	}
+	for _, versionFile := range repo.VersionFiles {
+		if !isRelativePath(versionFile.Path) {
+			return fmt.Errorf("invalid version file path: %q of repository %s, it must be relative to the repository",
+				versionFile.Path, repo.Address)
+		}
+		if (versionFile.Pattern == "") == (versionFile.Field == "") {
+			return fmt.Errorf("one of pattern and field is required by version file %s of repository %s",
+				versionFile.Path, repo.Address)
+		}
+		if _, err := regexp.Compile(versionFile.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern of version file %s, error: %v", versionFile.Path, err)
+		}
+	}
 	return nil
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
-func (r *Releaser) ValidateDelete() error {
-	releaserlog.Info("validate delete", "name", r.Name)
+// isRelativePath checks if the path is inside the repository
+func isRelativePath(filePath string) bool {
+	return filePath != "" && !path.IsAbs(filePath) && !strings.HasPrefix(path.Clean(filePath), "..")
+}
 
-	// TODO(user): fill in your validation logic upon object deletion.
+// validateSecrets checks if the referenced secrets exist and are able to access the git repositories,
+// the checks will be skipped if there is no client
+func (v *releaserValidator) validateSecrets(ctx context.Context, r *Releaser) (err error) {
+	if v.client == nil {
+		return
+	}
+
+	refs := []v1.SecretReference{r.Spec.Secret}
+	if r.Spec.GitOps != nil && r.Spec.GitOps.Enable {
+		refs = append(refs, r.GetGitOpsSecret())
+	}
+	for _, ref := range refs {
+		if ref.Name == "" {
+			continue
+		}
+		if ref.Namespace == "" {
+			ref.Namespace = r.Namespace
+		}
+
+		secret := &v1.Secret{}
+		if err = v.client.Get(ctx, types.NamespacedName{
+			Namespace: ref.Namespace,
+			Name:      ref.Name,
+		}, secret); err != nil {
+			return fmt.Errorf("cannot find secret %s/%s, error: %v", ref.Namespace, ref.Name, err)
+		}
+		if err = validateSecret(secret); err != nil {
+			return fmt.Errorf("invalid secret %s/%s: %v", ref.Namespace, ref.Name, err)
+		}
+	}
+	return
+}
+
+// validateSecret checks the type and the keys of a secret
+func validateSecret(secret *v1.Secret) (err error) {
+	switch secret.Type {
+	case v1.SecretTypeBasicAuth:
+		for _, key := range []string{v1.BasicAuthUsernameKey, v1.BasicAuthPasswordKey} {
+			if len(secret.Data[key]) == 0 {
+				return fmt.Errorf("key %s is required by type %s", key, secret.Type)
+			}
+		}
+	case v1.SecretTypeSSHAuth:
+		if len(secret.Data[v1.SSHAuthPrivateKey]) == 0 {
+			return fmt.Errorf("key %s is required by type %s", v1.SSHAuthPrivateKey, secret.Type)
+		}
+		if _, err = ssh.ParsePrivateKey(secret.Data[v1.SSHAuthPrivateKey]); err != nil {
+			return fmt.Errorf("cannot parse the SSH private key, error: %v", err)
+		}
+	default:
+		return fmt.Errorf("unsupported type %s, only %s and %s are supported",
+			secret.Type, v1.SecretTypeBasicAuth, v1.SecretTypeSSHAuth)
+	}
+	return
+}
+
+// scpLikeAddressPattern matches the SSH address, such as: git@github.com:kubesphere-sigs/ks-releaser.git
+var scpLikeAddressPattern = regexp.MustCompile(`^[\w.-]+@[\w.-]+:[\w./~-]+$`)
+
+// validateAddress checks if the address is a valid git repository URL
+func validateAddress(address string) error {
+	if address == "" {
+		return errors.New("the repository address is required")
+	}
+	if scpLikeAddressPattern.MatchString(address) {
+		return nil
+	}
+
+	gitURL, err := url.Parse(address)
+	if err != nil {
+		return fmt.Errorf("invalid repository address: %s, error: %v", address, err)
+	}
+	switch gitURL.Scheme {
+	case "http", "https", "ssh", "git":
+		if gitURL.Host == "" || strings.Trim(gitURL.Path, "/") == "" {
+			return fmt.Errorf("invalid repository address: %s, the host and path are required", address)
+		}
+	case "file":
+	default:
+		return fmt.Errorf("invalid repository address: %s, unsupported scheme '%s'", address, gitURL.Scheme)
+	}
 	return nil
 }
+
+// normalizeAddress makes the addresses of the same repository be the same
+func normalizeAddress(address string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(strings.ToLower(address), "/"), ".git")
+}
+
+// validateVersions checks if the versions follow the versioning scheme
+func (s *ReleaserSpec) validateVersions() (err error) {
+	var scheme versioning.Scheme
+	if scheme, err = s.GetVersioningScheme(time.Now); err != nil {
+		return
+	}
+
+	if s.Version != "" {
+		if err = scheme.Validate(s.Version); err != nil {
+			return
+		}
+	}
+	for _, repo := range s.Repositories {
+		if repo.Version == "" {
+			continue
+		}
+		if err = scheme.Validate(repo.Version); err != nil {
+			return fmt.Errorf("invalid version of repository %s: %v", repo.Address, err)
+		}
+	}
+
+	if s.NextVersion == nil {
+		return
+	}
+	strategy := s.NextVersion.Strategy
+	if strategy == BumpStrategyPreRelease && s.NextVersion.PreReleaseIdentifier == "" {
+		err = fmt.Errorf("preReleaseIdentifier is required by strategy %s", strategy)
+		return
+	}
+
+	// only the default strategy, calver and none are meaningful for calver
+	if s.Versioning != nil && s.Versioning.Scheme == versioning.CalVer {
+		switch strategy {
+		case "", BumpStrategyNone, BumpStrategyCalVer:
+		default:
+			err = fmt.Errorf("strategy %s is not supported by versioning scheme %s", strategy, versioning.CalVer)
+		}
+	}
+	return
+}
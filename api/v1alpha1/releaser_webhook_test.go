@@ -0,0 +1,444 @@
+package v1alpha1
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"testing"
+)
+
+func TestValidateVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    ReleaserSpec
+		wantErr bool
+	}{{
+		name: "semver by default",
+		spec: ReleaserSpec{Version: "v1.2.3", Repositories: []Repository{{Version: "v1.2.3-rc.0"}, {}}},
+	}, {
+		name:    "invalid semver",
+		spec:    ReleaserSpec{Version: "v1.2.3", Repositories: []Repository{{Version: "abc"}}},
+		wantErr: true,
+	}, {
+		name: "calver",
+		spec: ReleaserSpec{
+			Version:    "v21.09.0",
+			Versioning: &Versioning{Scheme: "calver", Format: "YY.0M.MICRO"},
+		},
+	}, {
+		name: "invalid calver",
+		spec: ReleaserSpec{
+			Version:    "v21.9.0",
+			Versioning: &Versioning{Scheme: "calver", Format: "YY.0M.MICRO"},
+		},
+		wantErr: true,
+	}, {
+		name:    "unknown scheme",
+		spec:    ReleaserSpec{Versioning: &Versioning{Scheme: "fake"}},
+		wantErr: true,
+	}, {
+		name: "unsupported strategy of calver",
+		spec: ReleaserSpec{
+			Versioning:  &Versioning{Scheme: "calver"},
+			NextVersion: &NextVersion{Strategy: BumpStrategyMinor},
+		},
+		wantErr: true,
+	}, {
+		name: "calver strategy of calver",
+		spec: ReleaserSpec{
+			Version:     "v21.09.0",
+			Versioning:  &Versioning{Scheme: "calver", Format: "YY.0M.MICRO"},
+			NextVersion: &NextVersion{Strategy: BumpStrategyCalVer},
+		},
+	}, {
+		name: "calver strategy with a semantic version",
+		spec: ReleaserSpec{
+			Version:     "v1.2.3",
+			NextVersion: &NextVersion{Strategy: BumpStrategyCalVer},
+		},
+	}, {
+		name: "calver strategy with a calendar version but without the calver scheme",
+		spec: ReleaserSpec{
+			Version:     "v2021.09.0",
+			NextVersion: &NextVersion{Strategy: BumpStrategyCalVer},
+		},
+		wantErr: true,
+	}, {
+		name: "prerelease without identifier",
+		spec: ReleaserSpec{
+			Version:     "v1.2.3",
+			NextVersion: &NextVersion{Strategy: BumpStrategyPreRelease},
+		},
+		wantErr: true,
+	}, {
+		name: "prerelease with identifier",
+		spec: ReleaserSpec{
+			Version:     "v1.2.3",
+			NextVersion: &NextVersion{Strategy: BumpStrategyPreRelease, PreReleaseIdentifier: "rc"},
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.validateVersions()
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    ReleaserSpec
+		wantErr string
+	}{{
+		name: "valid",
+		spec: ReleaserSpec{
+			Phase:   PhaseDraft,
+			Version: "v1.0.0",
+			Repositories: []Repository{{
+				Name:    "a",
+				Address: "https://github.com/kubesphere-sigs/a",
+				Action:  ActionRelease,
+			}, {
+				Name:    "b",
+				Address: "git@github.com:kubesphere-sigs/b.git",
+				Action:  ActionAuto,
+			}, {
+				Address: "https://bitbucket.org/kubesphere-sigs/c",
+				Action:  ActionTag,
+			}},
+			GitOps: &GitOps{Enable: true, Repository: Repository{Address: "https://github.com/kubesphere-sigs/d"}},
+		},
+	}, {
+		name:    "invalid phase",
+		spec:    ReleaserSpec{Phase: "fake"},
+		wantErr: "invalid phase",
+	}, {
+		name: "invalid address",
+		spec: ReleaserSpec{
+			Phase:        PhaseDraft,
+			Repositories: []Repository{{Address: "github.com/kubesphere-sigs/a"}},
+		},
+		wantErr: "unsupported scheme",
+	}, {
+		name: "missing address",
+		spec: ReleaserSpec{
+			Phase:        PhaseDraft,
+			Repositories: []Repository{{Name: "a"}},
+		},
+		wantErr: "address is required",
+	}, {
+		name: "duplicated names",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			Repositories: []Repository{{
+				Name:    "a",
+				Address: "https://github.com/kubesphere-sigs/a",
+			}, {
+				Name:    "a",
+				Address: "https://github.com/kubesphere-sigs/b",
+			}},
+		},
+		wantErr: "duplicated repository name",
+	}, {
+		name: "duplicated addresses",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			Repositories: []Repository{{
+				Address: "https://github.com/kubesphere-sigs/a",
+			}, {
+				Address: "https://github.com/kubesphere-sigs/a.git",
+			}},
+		},
+		wantErr: "duplicated repository address",
+	}, {
+		name: "unknown action",
+		spec: ReleaserSpec{
+			Phase:        PhaseDraft,
+			Repositories: []Repository{{Address: "https://github.com/kubesphere-sigs/a", Action: "fake"}},
+		},
+		wantErr: "invalid action",
+	}, {
+		name: "provider does not support release",
+		spec: ReleaserSpec{
+			Phase:        PhaseDraft,
+			Repositories: []Repository{{Address: "https://example.com/kubesphere-sigs/a", Action: ActionRelease}},
+		},
+		wantErr: "does not support action",
+	}, {
+		name: "auto action of a pre-release version",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			Repositories: []Repository{{
+				Address: "https://example.com/kubesphere-sigs/a",
+				Action:  ActionAuto,
+				Version: "v1.0.0-rc.0",
+			}},
+		},
+		wantErr: "does not support action pre-release",
+	}, {
+		name: "GitOps without address",
+		spec: ReleaserSpec{
+			Phase:  PhaseDraft,
+			GitOps: &GitOps{Enable: true},
+		},
+		wantErr: "required when GitOps is enabled",
+	}, {
+		name: "GitOps pull request mode",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			GitOps: &GitOps{Enable: true, Mode: GitOpsModePullRequest,
+				Repository: Repository{Address: "https://gitea.com/kubesphere-sigs/gitops"}},
+		},
+	}, {
+		name: "GitOps pull request mode with an unsupported provider",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			GitOps: &GitOps{Enable: true, Mode: GitOpsModePullRequest,
+				Repository: Repository{Address: "https://example.com/kubesphere-sigs/gitops"}},
+		},
+		wantErr: "does not support pull requests",
+	}, {
+		name: "GitOps updates",
+		spec: ReleaserSpec{
+			Phase:        PhaseDraft,
+			Repositories: []Repository{{Name: "a", Address: "https://github.com/kubesphere-sigs/a"}},
+			GitOps: &GitOps{Enable: true, Repository: Repository{Address: "https://github.com/kubesphere-sigs/d"},
+				Updates: []GitOpsUpdate{{Path: "apps/kustomization.yaml", Kind: GitOpsUpdateKustomization, Image: "a"},
+					{Path: "charts/a/values.yaml", Kind: GitOpsUpdateHelmValues, Repository: "a"}}},
+		},
+	}, {
+		name: "GitOps update out of the repository",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			GitOps: &GitOps{Enable: true, Repository: Repository{Address: "https://github.com/kubesphere-sigs/d"},
+				Updates: []GitOpsUpdate{{Path: "../values.yaml", Kind: GitOpsUpdateHelmValues}}},
+		},
+		wantErr: "invalid GitOps update path",
+	}, {
+		name: "GitOps update without fields",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			GitOps: &GitOps{Enable: true, Repository: Repository{Address: "https://github.com/kubesphere-sigs/d"},
+				Updates: []GitOpsUpdate{{Path: "deploy.yaml", Kind: GitOpsUpdateYAML}}},
+		},
+		wantErr: "fields are required",
+	}, {
+		name: "GitOps update of kustomization without image",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			GitOps: &GitOps{Enable: true, Repository: Repository{Address: "https://github.com/kubesphere-sigs/d"},
+				Updates: []GitOpsUpdate{{Path: "kustomization.yaml", Kind: GitOpsUpdateKustomization}}},
+		},
+		wantErr: "image is required",
+	}, {
+		name: "GitOps update with an unknown repository",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			GitOps: &GitOps{Enable: true, Repository: Repository{Address: "https://github.com/kubesphere-sigs/d"},
+				Updates: []GitOpsUpdate{{Path: "Chart.yaml", Kind: GitOpsUpdateHelmChart, Repository: "b"}}},
+		},
+		wantErr: "cannot find repository b",
+	}, {
+		name: "version files",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			Repositories: []Repository{{Address: "https://github.com/kubesphere-sigs/a", VersionFiles: []VersionFile{
+				{Path: "VERSION", Pattern: ".+"}, {Path: "package.json", Field: "version", TrimPrefix: true}}}},
+		},
+	}, {
+		name: "version file without pattern or field",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			Repositories: []Repository{{Address: "https://github.com/kubesphere-sigs/a",
+				VersionFiles: []VersionFile{{Path: "VERSION"}}}},
+		},
+		wantErr: "one of pattern and field is required",
+	}, {
+		name: "version file with an invalid pattern",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			Repositories: []Repository{{Address: "https://github.com/kubesphere-sigs/a",
+				VersionFiles: []VersionFile{{Path: "version.go", Pattern: "("}}}},
+		},
+		wantErr: "invalid pattern",
+	}, {
+		name: "version files with a pinned commit",
+		spec: ReleaserSpec{
+			Phase: PhaseDraft,
+			Repositories: []Repository{{Address: "https://github.com/kubesphere-sigs/a", Commit: "a-fake-commit",
+				VersionFiles: []VersionFile{{Path: "VERSION", Pattern: ".+"}}}},
+		},
+		wantErr: "not allowed with the pinned commit",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.validate()
+			if tt.wantErr == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateAddress(t *testing.T) {
+	assert.Nil(t, validateAddress("https://github.com/kubesphere-sigs/ks-releaser"))
+	assert.Nil(t, validateAddress("ssh://git@github.com/kubesphere-sigs/ks-releaser.git"))
+	assert.Nil(t, validateAddress("git@github.com:kubesphere-sigs/ks-releaser.git"))
+	assert.Nil(t, validateAddress("file:///tmp/ks-releaser"))
+	assert.NotNil(t, validateAddress(""))
+	assert.NotNil(t, validateAddress("https://github.com"))
+	assert.NotNil(t, validateAddress("ftp://github.com/kubesphere-sigs/ks-releaser"))
+	assert.NotNil(t, validateAddress("https://github.com/%zz"))
+}
+
+func TestValidateUpdate(t *testing.T) {
+	validator := &releaserValidator{}
+	oldReleaser := &Releaser{Spec: ReleaserSpec{Phase: PhaseDone}}
+	releaser := &Releaser{Spec: ReleaserSpec{Phase: PhaseReady}}
+	assert.NotNil(t, validator.ValidateUpdate(context.TODO(), oldReleaser, releaser))
+
+	oldReleaser.Spec.Phase = PhaseDraft
+	assert.Nil(t, validator.ValidateUpdate(context.TODO(), oldReleaser, releaser))
+
+	// an invalid Releaser created before the validation is able to be updated without changing the spec
+	oldReleaser = &Releaser{Spec: ReleaserSpec{Phase: PhaseDone, Repositories: []Repository{{Address: "abc"}}}}
+	releaser = oldReleaser.DeepCopy()
+	releaser.Annotations = map[string]string{"releaser.devops.kubesphere.io/hash": "fake"}
+	assert.Nil(t, validator.ValidateUpdate(context.TODO(), oldReleaser, releaser))
+
+	releaser.Spec.Phase = PhaseDraft
+	oldReleaser.Spec.Phase = PhaseDraft
+	releaser.Spec.Version = "v1.0.0"
+	assert.NotNil(t, validator.ValidateUpdate(context.TODO(), oldReleaser, releaser))
+}
+
+func TestReleaserValidator_Handle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, AddToScheme(scheme))
+	decoder, err := admission.NewDecoder(scheme)
+	assert.Nil(t, err)
+	validator := &releaserValidator{}
+	assert.Nil(t, validator.InjectDecoder(decoder))
+
+	newRequest := func(operation admissionv1.Operation, releaser, oldReleaser *Releaser) (req admission.Request) {
+		req.Operation = operation
+		req.Object.Raw, err = json.Marshal(releaser)
+		assert.Nil(t, err)
+		if oldReleaser != nil {
+			req.OldObject.Raw, err = json.Marshal(oldReleaser)
+			assert.Nil(t, err)
+		}
+		return
+	}
+	valid := &Releaser{Spec: ReleaserSpec{Phase: PhaseDraft, Version: "v1.0.0"}}
+	invalid := &Releaser{Spec: ReleaserSpec{Phase: "fake"}}
+
+	resp := validator.Handle(context.TODO(), newRequest(admissionv1.Create, valid, nil))
+	assert.True(t, resp.Allowed)
+
+	resp = validator.Handle(context.TODO(), newRequest(admissionv1.Create, invalid, nil))
+	assert.False(t, resp.Allowed)
+	assert.Equal(t, metav1.StatusReason("invalid phase"), resp.Result.Reason)
+
+	resp = validator.Handle(context.TODO(), newRequest(admissionv1.Update, invalid, valid))
+	assert.False(t, resp.Allowed)
+
+	resp = validator.Handle(context.TODO(), newRequest(admissionv1.Update, invalid, invalid))
+	assert.True(t, resp.Allowed)
+
+	resp = validator.Handle(context.TODO(), admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+		Operation: admissionv1.Create,
+	}})
+	assert.False(t, resp.Allowed)
+}
+
+func TestValidateSecrets(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.Nil(t, err)
+	privateKeyData := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+
+	newSecret := func(name string, secretType v1.SecretType, data map[string]string) *v1.Secret {
+		secret := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "fake", Name: name},
+			Type:       secretType,
+			Data:       map[string][]byte{},
+		}
+		for key, value := range data {
+			secret.Data[key] = []byte(value)
+		}
+		return secret
+	}
+	validator := &releaserValidator{client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme,
+		newSecret("basic", v1.SecretTypeBasicAuth, map[string]string{"username": "user", "password": "token"}),
+		newSecret("ssh", v1.SecretTypeSSHAuth, map[string]string{"ssh-privatekey": string(privateKeyData)}),
+		newSecret("missing-password", v1.SecretTypeBasicAuth, map[string]string{"username": "user"}),
+		newSecret("invalid-ssh", v1.SecretTypeSSHAuth, map[string]string{"ssh-privatekey": "fake"}),
+		newSecret("opaque", v1.SecretTypeOpaque, nil))}
+
+	tests := []struct {
+		name         string
+		secret       string
+		gitOpsSecret string
+		wantErr      string
+	}{{
+		name:   "basic auth",
+		secret: "basic",
+	}, {
+		name:         "ssh auth",
+		secret:       "basic",
+		gitOpsSecret: "ssh",
+	}, {
+		name: "no secret",
+	}, {
+		name:    "secret not found",
+		secret:  "fake",
+		wantErr: "cannot find secret fake/fake",
+	}, {
+		name:    "missing key",
+		secret:  "missing-password",
+		wantErr: "key password is required",
+	}, {
+		name:         "invalid SSH key",
+		secret:       "basic",
+		gitOpsSecret: "invalid-ssh",
+		wantErr:      "cannot parse the SSH private key",
+	}, {
+		name:    "unsupported type",
+		secret:  "opaque",
+		wantErr: "unsupported type Opaque",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaser := &Releaser{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "fake"},
+				Spec: ReleaserSpec{
+					Secret: v1.SecretReference{Name: tt.secret},
+					GitOps: &GitOps{Enable: true, Secret: v1.SecretReference{Name: tt.gitOpsSecret}},
+				},
+			}
+			err := validator.validateSecrets(context.TODO(), releaser)
+			if tt.wantErr == "" {
+				assert.Nil(t, err)
+			} else if assert.NotNil(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2021 The KubeSphere Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+)
+
+// GetReleaseStages groups the repositories into ordered stages according to their dependencies.
+// Each stage holds the indexes of the repositories, a repository only depends on the ones in the previous stages.
+func (s *ReleaserSpec) GetReleaseStages() (stages [][]int, err error) {
+	repos := s.Repositories
+	indexes := make(map[string][]int, len(repos))
+	for i := range repos {
+		indexes[repos[i].Name] = append(indexes[repos[i].Name], i)
+	}
+
+	// count the dependencies of each repository
+	inDegrees := make([]int, len(repos))
+	dependents := make([][]int, len(repos))
+	for i := range repos {
+		for _, dependency := range repos[i].DependsOn {
+			dependencyIndexes, ok := indexes[dependency]
+			if !ok {
+				err = fmt.Errorf("repository %s depends on an unknown repository: %s", repos[i].Name, dependency)
+				return
+			}
+			for _, index := range dependencyIndexes {
+				inDegrees[i]++
+				dependents[index] = append(dependents[index], i)
+			}
+		}
+	}
+
+	var current []int
+	for i := range repos {
+		if inDegrees[i] == 0 {
+			current = append(current, i)
+		}
+	}
+
+	count := 0
+	for len(current) > 0 {
+		stages = append(stages, current)
+		count += len(current)
+
+		var next []int
+		for _, index := range current {
+			for _, dependent := range dependents[index] {
+				if inDegrees[dependent]--; inDegrees[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		// keep the same order with the repositories
+		sort.Ints(next)
+		current = next
+	}
+
+	if count != len(repos) {
+		stages = nil
+		err = fmt.Errorf("found circular dependencies between the repositories")
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package v1alpha1
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGetReleaseStages(t *testing.T) {
+	tests := []struct {
+		name       string
+		repos      []Repository
+		wantStages [][]int
+		wantErr    bool
+	}{{
+		name: "without repositories",
+	}, {
+		name:       "without dependencies",
+		repos:      []Repository{{Name: "a"}, {Name: "b"}},
+		wantStages: [][]int{{0, 1}},
+	}, {
+		name: "have dependencies",
+		repos: []Repository{{
+			Name:      "installer",
+			DependsOn: []string{"api", "console"},
+		}, {
+			Name:      "console",
+			DependsOn: []string{"api"},
+		}, {
+			Name: "api",
+		}, {
+			Name: "docs",
+		}},
+		wantStages: [][]int{{2, 3}, {1}, {0}},
+	}, {
+		name: "unknown dependency",
+		repos: []Repository{{
+			Name:      "console",
+			DependsOn: []string{"api"},
+		}},
+		wantErr: true,
+	}, {
+		name: "circular dependencies",
+		repos: []Repository{{
+			Name:      "a",
+			DependsOn: []string{"b"},
+		}, {
+			Name:      "b",
+			DependsOn: []string{"a"},
+		}, {
+			Name: "c",
+		}},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := &ReleaserSpec{Repositories: tt.repos}
+			stages, err := spec.GetReleaseStages()
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.Nil(t, err)
+			}
+			assert.Equal(t, tt.wantStages, stages)
+		})
+	}
+}
@@ -21,9 +21,25 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Author) DeepCopyInto(out *Author) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Author.
+func (in *Author) DeepCopy() *Author {
+	if in == nil {
+		return nil
+	}
+	out := new(Author)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Condition) DeepCopyInto(out *Condition) {
 	*out = *in
@@ -42,8 +58,15 @@ func (in *Condition) DeepCopy() *Condition {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GitOps) DeepCopyInto(out *GitOps) {
 	*out = *in
-	out.Repository = in.Repository
+	in.Repository.DeepCopyInto(&out.Repository)
 	out.Secret = in.Secret
+	if in.Updates != nil {
+		in, out := &in.Updates, &out.Updates
+		*out = make([]GitOpsUpdate, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOps.
@@ -56,6 +79,122 @@ func (in *GitOps) DeepCopy() *GitOps {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GitOpsUpdate) DeepCopyInto(out *GitOpsUpdate) {
+	*out = *in
+	if in.Fields != nil {
+		in, out := &in.Fields, &out.Fields
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GitOpsUpdate.
+func (in *GitOpsUpdate) DeepCopy() *GitOpsUpdate {
+	if in == nil {
+		return nil
+	}
+	out := new(GitOpsUpdate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IssueStatus) DeepCopyInto(out *IssueStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IssueStatus.
+func (in *IssueStatus) DeepCopy() *IssueStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IssueStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NextVersion) DeepCopyInto(out *NextVersion) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NextVersion.
+func (in *NextVersion) DeepCopy() *NextVersion {
+	if in == nil {
+		return nil
+	}
+	out := new(NextVersion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PullRequestStatus) DeepCopyInto(out *PullRequestStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PullRequestStatus.
+func (in *PullRequestStatus) DeepCopy() *PullRequestStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PullRequestStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleaseNotes) DeepCopyInto(out *ReleaseNotes) {
+	*out = *in
+	if in.TemplateRef != nil {
+		in, out := &in.TemplateRef, &out.TemplateRef
+		*out = new(v1.ConfigMapKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaseNotes.
+func (in *ReleaseNotes) DeepCopy() *ReleaseNotes {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleaseNotes)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReleasePlan) DeepCopyInto(out *ReleasePlan) {
+	*out = *in
+	if in.PlanTime != nil {
+		in, out := &in.PlanTime, &out.PlanTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextReleasers != nil {
+		in, out := &in.NextReleasers, &out.NextReleasers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]RepositoryPlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleasePlan.
+func (in *ReleasePlan) DeepCopy() *ReleasePlan {
+	if in == nil {
+		return nil
+	}
+	out := new(ReleasePlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Releaser) DeepCopyInto(out *Releaser) {
 	*out = *in
@@ -121,14 +260,41 @@ func (in *ReleaserSpec) DeepCopyInto(out *ReleaserSpec) {
 	if in.Repositories != nil {
 		in, out := &in.Repositories, &out.Repositories
 		*out = make([]Repository, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	if in.GitOps != nil {
 		in, out := &in.GitOps, &out.GitOps
 		*out = new(GitOps)
-		**out = **in
+		(*in).DeepCopyInto(*out)
 	}
 	out.Secret = in.Secret
+	if in.ReleaseNotes != nil {
+		in, out := &in.ReleaseNotes, &out.ReleaseNotes
+		*out = new(ReleaseNotes)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NextVersion != nil {
+		in, out := &in.NextVersion, &out.NextVersion
+		*out = new(NextVersion)
+		**out = **in
+	}
+	if in.Versioning != nil {
+		in, out := &in.Versioning, &out.Versioning
+		*out = new(Versioning)
+		**out = **in
+	}
+	if in.Signing != nil {
+		in, out := &in.Signing, &out.Signing
+		*out = new(Signing)
+		**out = **in
+	}
+	if in.Author != nil {
+		in, out := &in.Author, &out.Author
+		*out = new(Author)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaserSpec.
@@ -152,11 +318,37 @@ func (in *ReleaserStatus) DeepCopyInto(out *ReleaserStatus) {
 		in, out := &in.CompletionTime, &out.CompletionTime
 		*out = (*in).DeepCopy()
 	}
+	if in.LastAttemptTime != nil {
+		in, out := &in.LastAttemptTime, &out.LastAttemptTime
+		*out = (*in).DeepCopy()
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]Condition, len(*in))
 		copy(*out, *in)
 	}
+	if in.Repositories != nil {
+		in, out := &in.Repositories, &out.Repositories
+		*out = make([]RepositoryStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(ReleasePlan)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PullRequest != nil {
+		in, out := &in.PullRequest, &out.PullRequest
+		*out = new(PullRequestStatus)
+		**out = **in
+	}
+	if in.Issue != nil {
+		in, out := &in.Issue, &out.Issue
+		*out = new(IssueStatus)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReleaserStatus.
@@ -172,6 +364,16 @@ func (in *ReleaserStatus) DeepCopy() *ReleaserStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Repository) DeepCopyInto(out *Repository) {
 	*out = *in
+	if in.DependsOn != nil {
+		in, out := &in.DependsOn, &out.DependsOn
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.VersionFiles != nil {
+		in, out := &in.VersionFiles, &out.VersionFiles
+		*out = make([]VersionFile, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Repository.
@@ -183,3 +385,92 @@ func (in *Repository) DeepCopy() *Repository {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryPlan) DeepCopyInto(out *RepositoryPlan) {
+	*out = *in
+	if in.VersionFiles != nil {
+		in, out := &in.VersionFiles, &out.VersionFiles
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryPlan.
+func (in *RepositoryPlan) DeepCopy() *RepositoryPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RepositoryStatus) DeepCopyInto(out *RepositoryStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RepositoryStatus.
+func (in *RepositoryStatus) DeepCopy() *RepositoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RepositoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Signing) DeepCopyInto(out *Signing) {
+	*out = *in
+	out.Secret = in.Secret
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Signing.
+func (in *Signing) DeepCopy() *Signing {
+	if in == nil {
+		return nil
+	}
+	out := new(Signing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VersionFile) DeepCopyInto(out *VersionFile) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VersionFile.
+func (in *VersionFile) DeepCopy() *VersionFile {
+	if in == nil {
+		return nil
+	}
+	out := new(VersionFile)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Versioning) DeepCopyInto(out *Versioning) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Versioning.
+func (in *Versioning) DeepCopy() *Versioning {
+	if in == nil {
+		return nil
+	}
+	out := new(Versioning)
+	in.DeepCopyInto(out)
+	return out
+}
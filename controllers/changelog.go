@@ -0,0 +1,361 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
+	"io/ioutil"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// releaseCommit represents a commit which will be listed in the release notes
+type releaseCommit struct {
+	Hash      string
+	ShortHash string
+	// Type is the type of a conventional commit, such as: feat, fix
+	Type     string
+	Scope    string
+	Subject  string
+	Body     string
+	Author   string
+	Email    string
+	Breaking bool
+	// PullRequests are the numbers of the pull requests which are mentioned in the message
+	PullRequests []int
+}
+
+var conventionalCommitPattern = regexp.MustCompile(`^(\w+)(?:\(([^)]*)\))?(!)?:\s*(.+)$`)
+var pullRequestPattern = regexp.MustCompile(`#(\d+)`)
+
+// changelogCommitType and changelogCommitScope are used by the commit which updates the changelog file
+const (
+	changelogCommitType  = "chore"
+	changelogCommitScope = "changelog"
+)
+
+// newReleaseCommit parses the message of a commit
+func newReleaseCommit(commit *object.Commit) (result releaseCommit) {
+	result = releaseCommit{
+		Hash:      commit.Hash.String(),
+		ShortHash: commit.Hash.String()[:7],
+		Author:    commit.Author.Name,
+		Email:     commit.Author.Email,
+	}
+
+	message := strings.TrimSpace(commit.Message)
+	result.Subject = message
+	if index := strings.Index(message, "\n"); index > 0 {
+		result.Subject = strings.TrimSpace(message[:index])
+		result.Body = strings.TrimSpace(message[index:])
+	}
+
+	if groups := conventionalCommitPattern.FindStringSubmatch(result.Subject); groups != nil {
+		result.Type = strings.ToLower(groups[1])
+		result.Scope = groups[2]
+		result.Breaking = groups[3] == "!"
+		result.Subject = groups[4]
+	}
+	if strings.Contains(result.Body, "BREAKING CHANGE") {
+		result.Breaking = true
+	}
+
+	for _, groups := range pullRequestPattern.FindAllStringSubmatch(message, -1) {
+		if number, err := strconv.Atoi(groups[1]); err == nil {
+			result.PullRequests = append(result.PullRequests, number)
+		}
+	}
+	return
+}
+
+// isChangelogCommit checks if the commit was created by ks-releaser for updating the changelog file
+func (c releaseCommit) isChangelogCommit() bool {
+	return c.Type == changelogCommitType && c.Scope == changelogCommitScope
+}
+
+// isVersionCommit checks if the commit was created by ks-releaser for updating the version files
+func (c releaseCommit) isVersionCommit() bool {
+	return c.Type == changelogCommitType && c.Scope == versionCommitScope
+}
+
+// getCommitsSinceLastTag returns the commits between the target and the nearest tagged ancestor of it.
+// The currentTag will be ignored, so it works well no matter the current tag exists or not. The tags which are
+// invalid in the scheme, such as: nightly, are ignored as well, all the tags are taken if the scheme is nil.
+func getCommitsSinceLastTag(r *git.Repository, target plumbing.Hash, currentTag string, scheme versioning.Scheme) (
+	commits []releaseCommit, previousTag string, err error) {
+	taggedCommits := map[plumbing.Hash]string{}
+	var tags storer.ReferenceIter
+	if tags, err = r.Tags(); err != nil {
+		return
+	}
+	if err = tags.ForEach(func(ref *plumbing.Reference) error {
+		tag := ref.Name().Short()
+		if tag == currentTag || (scheme != nil && scheme.Validate(tag) != nil) {
+			return nil
+		}
+		if hash, tagErr := getTagCommit(r, tag); tagErr == nil {
+			taggedCommits[hash] = tag
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	var targetCommit *object.Commit
+	if targetCommit, err = r.CommitObject(target); err != nil {
+		return
+	}
+
+	// find the nearest tagged ancestor
+	var previousCommit *object.Commit
+	if err = object.NewCommitPreorderIter(targetCommit, nil, nil).ForEach(func(commit *object.Commit) error {
+		if tag, ok := taggedCommits[commit.Hash]; ok {
+			previousTag, previousCommit = tag, commit
+			return storer.ErrStop
+		}
+		return nil
+	}); err != nil {
+		return
+	}
+
+	// all the commits of the previous tag are excluded
+	seen := map[plumbing.Hash]bool{}
+	if previousCommit != nil {
+		if err = object.NewCommitPreorderIter(previousCommit, nil, nil).ForEach(func(commit *object.Commit) error {
+			seen[commit.Hash] = true
+			return nil
+		}); err != nil {
+			return
+		}
+	}
+
+	err = object.NewCommitPreorderIter(targetCommit, seen, nil).ForEach(func(commit *object.Commit) error {
+		if commit.NumParents() > 1 {
+			// skip the merge commits
+			return nil
+		}
+		if releaseCommit := newReleaseCommit(commit); !releaseCommit.isChangelogCommit() &&
+			!releaseCommit.isVersionCommit() {
+			commits = append(commits, releaseCommit)
+		}
+		return nil
+	})
+	return
+}
+
+// releaseNotesGroup is a group of the commits in the release notes
+type releaseNotesGroup struct {
+	Title   string
+	Commits []releaseCommit
+}
+
+// releaseNotesData is the data of rendering the release notes
+type releaseNotesData struct {
+	Version         string
+	PreviousVersion string
+	Repository      devopsv1alpha1.Repository
+	Commits         []releaseCommit
+	// Authors are the unique names of the commit authors
+	Authors []string
+	// PullRequests are the unique numbers of the pull requests which are mentioned in the commits
+	PullRequests    []int
+	Groups          []releaseNotesGroup
+	BreakingChanges []releaseCommit
+}
+
+var releaseNotesGroupTitles = []struct {
+	commitType string
+	title      string
+}{
+	{commitType: "feat", title: "Features"},
+	{commitType: "fix", title: "Bug Fixes"},
+	{commitType: "perf", title: "Performance Improvements"},
+	{commitType: "docs", title: "Documentation"},
+}
+
+const defaultReleaseNotesTemplate = `{{- if .BreakingChanges }}### Breaking Changes
+
+{{ range .BreakingChanges }}* {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Subject }} ({{ .ShortHash }})
+{{ end }}
+{{ end -}}
+{{- range .Groups }}### {{ .Title }}
+
+{{ range .Commits }}* {{ if .Scope }}**{{ .Scope }}:** {{ end }}{{ .Subject }} ({{ .ShortHash }})
+{{ end }}
+{{ end -}}`
+
+// renderReleaseNotes renders the release notes of the commits with the template, the default template groups
+// the commits by the types of them
+func renderReleaseNotes(releaseNotesTemplate string, repo devopsv1alpha1.Repository, previousVersion string,
+	commits []releaseCommit) (notes string, err error) {
+	if releaseNotesTemplate == "" {
+		releaseNotesTemplate = defaultReleaseNotesTemplate
+	}
+	data := releaseNotesData{
+		Version:         repo.Version,
+		PreviousVersion: previousVersion,
+		Repository:      repo,
+		Commits:         commits,
+	}
+
+	authors := map[string]bool{}
+	pullRequests := map[int]bool{}
+	for _, commit := range commits {
+		if !authors[commit.Author] {
+			authors[commit.Author] = true
+			data.Authors = append(data.Authors, commit.Author)
+		}
+		for _, number := range commit.PullRequests {
+			if !pullRequests[number] {
+				pullRequests[number] = true
+				data.PullRequests = append(data.PullRequests, number)
+			}
+		}
+	}
+
+	groups := map[string]*releaseNotesGroup{}
+	for _, item := range releaseNotesGroupTitles {
+		group := &releaseNotesGroup{Title: item.title}
+		groups[item.commitType] = group
+	}
+	others := &releaseNotesGroup{Title: "Others"}
+	for _, commit := range commits {
+		if commit.Breaking {
+			data.BreakingChanges = append(data.BreakingChanges, commit)
+		}
+		if group, ok := groups[commit.Type]; ok {
+			group.Commits = append(group.Commits, commit)
+		} else {
+			others.Commits = append(others.Commits, commit)
+		}
+	}
+	for _, item := range releaseNotesGroupTitles {
+		if group := groups[item.commitType]; len(group.Commits) > 0 {
+			data.Groups = append(data.Groups, *group)
+		}
+	}
+	if len(others.Commits) > 0 {
+		data.Groups = append(data.Groups, *others)
+	}
+
+	var tpl *template.Template
+	if tpl, err = template.New("releaseNotes").Parse(releaseNotesTemplate); err != nil {
+		return
+	}
+	buf := &bytes.Buffer{}
+	if err = tpl.Execute(buf, data); err == nil {
+		notes = strings.TrimSpace(buf.String())
+	}
+	return
+}
+
+// getReleaseNotes returns the release notes of the commits between the previous tag and the target
+func getReleaseNotes(r *git.Repository, repo devopsv1alpha1.Repository, option releaseOption,
+	target plumbing.Hash) (notes string, err error) {
+	var commits []releaseCommit
+	var previousTag string
+	if commits, previousTag, err = getCommitsSinceLastTag(r, target, repo.Version, option.scheme); err == nil {
+		notes, err = renderReleaseNotes(option.releaseNotesTemplate, repo, previousTag, commits)
+	}
+	return
+}
+
+// generateReleaseNotes generates the release notes of a repository,
+// the commits end with the tag if it exists, or the commit to tag
+func generateReleaseNotes(repo devopsv1alpha1.Repository, option releaseOption) (notes string, err error) {
+	unlock := lockRepoCacheDir(option.cacheDir, repo.Address)
+	defer unlock()
+
+	var gitRepo *git.Repository
+	if gitRepo, err = clone(repo.Address, repo.Branch, getAuth(option.secret), option.cacheDir); err != nil {
+		err = fmt.Errorf("failed to clone %s, error: %v", repo.Address, err)
+		return
+	}
+
+	var target plumbing.Hash
+	if target, err = getTagCommit(gitRepo, repo.Version); err == git.ErrTagNotFound {
+		target, err = getTagTarget(gitRepo, repo.Commit, repo.Branch)
+	}
+	if err == nil {
+		notes, err = getReleaseNotes(gitRepo, repo, option, target)
+	}
+	return
+}
+
+// updateChangelog writes the release notes into the changelog file, then commits and pushes it.
+// It returns the new commit which should be tagged.
+func updateChangelog(r *git.Repository, repo devopsv1alpha1.Repository, option releaseOption, target plumbing.Hash) (
+	newTarget plumbing.Hash, err error) {
+	newTarget = target
+
+	var head *plumbing.Reference
+	if head, err = r.Head(); err != nil {
+		return
+	}
+	if head.Hash() != target {
+		err = fmt.Errorf("cannot update the changelog when tagging commit %s which is not the head of branch %s",
+			target.String(), repo.Branch)
+		return
+	}
+
+	var wd *git.Worktree
+	if wd, err = r.Worktree(); err != nil {
+		return
+	}
+	changelogFile := path.Join(wd.Filesystem.Root(), option.changelogFile)
+
+	var data []byte
+	if data, err = ioutil.ReadFile(changelogFile); err != nil && !os.IsNotExist(err) {
+		return
+	}
+	versionTitle := fmt.Sprintf("## %s\n", repo.Version)
+	if strings.Contains(string(data), versionTitle) {
+		// the changelog was updated by a previous attempt
+		return
+	}
+
+	var notes string
+	if notes, err = getReleaseNotes(r, repo, option, target); err != nil {
+		return
+	}
+
+	data = []byte(insertChangelogEntry(string(data), versionTitle+"\n"+notes+"\n"))
+	if err = ioutil.WriteFile(changelogFile, data, 0644); err != nil {
+		return
+	}
+	commitMessage := fmt.Sprintf("%s(%s): release %s", changelogCommitType, changelogCommitScope, repo.Version)
+	if err = addAndCommit(r, option.identity, commitMessage); err != nil {
+		return
+	}
+	if err = pushBranch(r, repo.Branch, getAuth(option.secret)); err != nil {
+		return
+	}
+
+	if head, err = r.Head(); err == nil {
+		newTarget = head.Hash()
+	}
+	return
+}
+
+// insertChangelogEntry inserts the entry after the title of the changelog
+func insertChangelogEntry(changelog, entry string) string {
+	if changelog == "" {
+		return "# Changelog\n\n" + entry
+	}
+	if strings.HasPrefix(changelog, "# ") {
+		if index := strings.Index(changelog, "\n"); index > 0 {
+			return changelog[:index+1] + "\n" + entry + "\n" + strings.TrimLeft(changelog[index+1:], "\n")
+		}
+		return changelog + "\n\n" + entry
+	}
+	return entry + "\n" + changelog
+}
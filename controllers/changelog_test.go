@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func Test_newReleaseCommit(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		verify  func(t *testing.T, commit releaseCommit)
+	}{{
+		name:    "a normal commit",
+		message: "Fix the typo of README",
+		verify: func(t *testing.T, commit releaseCommit) {
+			assert.Equal(t, "", commit.Type)
+			assert.Equal(t, "Fix the typo of README", commit.Subject)
+			assert.False(t, commit.Breaking)
+		},
+	}, {
+		name:    "a conventional commit",
+		message: "feat(api): support dry-run mode (#12)\n\nfixes #10",
+		verify: func(t *testing.T, commit releaseCommit) {
+			assert.Equal(t, "feat", commit.Type)
+			assert.Equal(t, "api", commit.Scope)
+			assert.Equal(t, "support dry-run mode (#12)", commit.Subject)
+			assert.Equal(t, "fixes #10", commit.Body)
+			assert.Equal(t, []int{12, 10}, commit.PullRequests)
+			assert.False(t, commit.Breaking)
+		},
+	}, {
+		name:    "a breaking change",
+		message: "refactor!: remove the deprecated fields",
+		verify: func(t *testing.T, commit releaseCommit) {
+			assert.Equal(t, "refactor", commit.Type)
+			assert.True(t, commit.Breaking)
+		},
+	}, {
+		name:    "a breaking change in the body",
+		message: "fix: change the default branch\n\nBREAKING CHANGE: use main as the default branch",
+		verify: func(t *testing.T, commit releaseCommit) {
+			assert.True(t, commit.Breaking)
+		},
+	}, {
+		name:    "the changelog commit",
+		message: "chore(changelog): release v0.0.1",
+		verify: func(t *testing.T, commit releaseCommit) {
+			assert.True(t, commit.isChangelogCommit())
+		},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commit := newReleaseCommit(&object.Commit{
+				Hash:    plumbing.NewHash("b6e5b5ab76e9f1bba8df18a7ff1b6d1b2a3bb68f"),
+				Message: tt.message,
+				Author:  object.Signature{Name: "test", Email: "test@test.com"},
+			})
+			assert.Equal(t, "b6e5b5a", commit.ShortHash)
+			assert.Equal(t, "test", commit.Author)
+			tt.verify(t, commit)
+		})
+	}
+}
+
+func Test_getCommitsSinceLastTag(t *testing.T) {
+	_, repo := newLocalRepository(t, "first commit", "feat: second commit")
+	head, err := repo.Head()
+	assert.Nil(t, err)
+
+	// all commits are included when there is no tag
+	commits, previousTag, err := getCommitsSinceLastTag(repo, head.Hash(), "v0.0.1", versioning.NewSemVer())
+	assert.Nil(t, err)
+	assert.Equal(t, "", previousTag)
+	assert.Equal(t, 2, len(commits))
+
+	_, err = repo.CreateTag("v0.0.1", head.Hash(), nil)
+	assert.Nil(t, err)
+
+	// the current tag is ignored
+	commits, previousTag, err = getCommitsSinceLastTag(repo, head.Hash(), "v0.0.1", versioning.NewSemVer())
+	assert.Nil(t, err)
+	assert.Equal(t, "", previousTag)
+	assert.Equal(t, 2, len(commits))
+
+	wd, err := repo.Worktree()
+	assert.Nil(t, err)
+	for _, message := range []string{"fix: third commit", "chore(changelog): release v0.0.2"} {
+		_, err = wd.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		})
+		assert.Nil(t, err)
+	}
+	head, err = repo.Head()
+	assert.Nil(t, err)
+
+	// only the commits after the previous tag are included, the changelog commit is skipped
+	commits, previousTag, err = getCommitsSinceLastTag(repo, head.Hash(), "v0.0.2", versioning.NewSemVer())
+	assert.Nil(t, err)
+	assert.Equal(t, "v0.0.1", previousTag)
+	if assert.Equal(t, 1, len(commits)) {
+		assert.Equal(t, "third commit", commits[0].Subject)
+	}
+
+	// the tag which is not a version is skipped
+	parent, err := repo.CommitObject(head.Hash())
+	assert.Nil(t, err)
+	_, err = repo.CreateTag("nightly", parent.ParentHashes[0], nil)
+	assert.Nil(t, err)
+	commits, previousTag, err = getCommitsSinceLastTag(repo, head.Hash(), "v0.0.2", versioning.NewSemVer())
+	assert.Nil(t, err)
+	assert.Equal(t, "v0.0.1", previousTag)
+	assert.Equal(t, 1, len(commits))
+
+	// all the tags are taken without a scheme
+	_, previousTag, err = getCommitsSinceLastTag(repo, head.Hash(), "v0.0.2", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "nightly", previousTag)
+}
+
+func Test_renderReleaseNotes(t *testing.T) {
+	repo := v1alpha1.Repository{Name: "console", Version: "v0.0.2"}
+	commits := []releaseCommit{
+		{ShortHash: "1111111", Type: "fix", Subject: "fix a bug", Author: "bob", PullRequests: []int{2}},
+		{ShortHash: "2222222", Type: "feat", Scope: "api", Subject: "add a feature", Breaking: true, Author: "alice",
+			PullRequests: []int{1, 2}},
+		{ShortHash: "3333333", Subject: "update README", Author: "bob"},
+	}
+	notes, err := renderReleaseNotes("", repo, "v0.0.1", commits)
+	assert.Nil(t, err)
+	assert.Equal(t, `### Breaking Changes
+
+* **api:** add a feature (2222222)
+
+### Features
+
+* **api:** add a feature (2222222)
+
+### Bug Fixes
+
+* fix a bug (1111111)
+
+### Others
+
+* update README (3333333)`, notes)
+
+	notes, err = renderReleaseNotes("", repo, "v0.0.1", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "", notes)
+
+	// a custom template
+	notes, err = renderReleaseNotes(`{{.Repository.Name}} {{.Version}} since {{.PreviousVersion}}
+authors: {{range .Authors}}@{{.}} {{end}}
+pull requests: {{range .PullRequests}}#{{.}} {{end}}`, repo, "v0.0.1", commits)
+	assert.Nil(t, err)
+	assert.Equal(t, "console v0.0.2 since v0.0.1\nauthors: @bob @alice \npull requests: #2 #1", notes)
+
+	// an invalid template
+	_, err = renderReleaseNotes("{{.Fake}", repo, "v0.0.1", commits)
+	assert.NotNil(t, err)
+}
+
+func Test_insertChangelogEntry(t *testing.T) {
+	entry := "## v0.0.2\n\n* fix a bug\n"
+	assert.Equal(t, "# Changelog\n\n"+entry, insertChangelogEntry("", entry))
+	assert.Equal(t, "# Changelog\n\n"+entry+"\n## v0.0.1\n",
+		insertChangelogEntry("# Changelog\n\n## v0.0.1\n", entry))
+	assert.Equal(t, entry+"\n## v0.0.1\n", insertChangelogEntry("## v0.0.1\n", entry))
+}
+
+func Test_tagRepositoryWithChangelog(t *testing.T) {
+	dir, remote := newLocalRepository(t, "first commit", "feat: second commit")
+	head, err := remote.Head()
+	assert.Nil(t, err)
+
+	repo := v1alpha1.Repository{
+		Address: dir,
+		Branch:  "master",
+		Version: "v0.0.1",
+	}
+	option := releaseOption{identity: newGitIdentity("user"), cacheDir: t.TempDir(), changelogFile: "CHANGELOG.md"}
+	status := &v1alpha1.RepositoryStatus{}
+	err = tagRepository(repo, option, status)
+	assert.Nil(t, err)
+
+	// the changelog commit was pushed and tagged
+	newHead, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, head.Hash(), newHead.Hash())
+	assert.Equal(t, newHead.Hash().String(), status.Commit)
+	commit, err := remote.CommitObject(newHead.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, "chore(changelog): release v0.0.1", commit.Message)
+	file, err := commit.File("CHANGELOG.md")
+	assert.Nil(t, err)
+	content, err := file.Contents()
+	assert.Nil(t, err)
+	assert.Contains(t, content, "## v0.0.1\n\n### Features\n\n* second commit")
+
+	// cannot update the changelog when tagging an old commit
+	repo.Version = "v0.0.2"
+	repo.Commit = head.Hash().String()
+	err = tagRepository(repo, option, &v1alpha1.RepositoryStatus{})
+	assert.NotNil(t, err)
+
+	// the release notes of a tag
+	notes, err := generateReleaseNotes(v1alpha1.Repository{Address: dir, Branch: "master", Version: "v0.0.1"}, option)
+	assert.Nil(t, err)
+	assert.Contains(t, notes, "* second commit")
+
+	// the release notes of a tag which does not exist
+	notes, err = generateReleaseNotes(v1alpha1.Repository{Address: dir, Branch: "master", Version: "v0.0.3"}, option)
+	assert.Nil(t, err)
+	assert.Equal(t, "", notes)
+}
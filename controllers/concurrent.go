@@ -0,0 +1,26 @@
+package controllers
+
+import "sync"
+
+// runConcurrently runs the task of each index with a limited concurrency, returns once all the tasks are done
+func runConcurrently(count, concurrency int, task func(index int)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	wg := sync.WaitGroup{}
+	limit := make(chan struct{}, concurrency)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() {
+				<-limit
+			}()
+
+			task(index)
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunConcurrently(t *testing.T) {
+	var running, maxRunning int32
+	mutex := sync.Mutex{}
+	done := make([]bool, 10)
+
+	runConcurrently(len(done), 3, func(index int) {
+		current := atomic.AddInt32(&running, 1)
+		mutex.Lock()
+		if current > maxRunning {
+			maxRunning = current
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+		done[index] = true
+		atomic.AddInt32(&running, -1)
+	})
+
+	assert.LessOrEqual(t, maxRunning, int32(3))
+	for i := range done {
+		assert.True(t, done[i])
+	}
+
+	// run in sequence with an invalid concurrency
+	count := 0
+	runConcurrently(2, 0, func(index int) {
+		count++
+	})
+	assert.Equal(t, 2, count)
+}
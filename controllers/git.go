@@ -6,10 +6,10 @@ import (
 	"github.com/go-git/go-git/v5/config"
 	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
-	"github.com/go-git/go-git/v5/plumbing/storer"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
 	"github.com/kubesphere-sigs/ks-releaser/controllers/internal_scm"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
 	"golang.org/x/crypto/ssh"
 	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 	gitssh "gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
@@ -19,7 +19,7 @@ import (
 	"os"
 	"path"
 	"strings"
-	"time"
+	"sync"
 )
 
 /**
@@ -27,29 +27,32 @@ TODO make these functions into a struct
 For example, we can share parts of the variables, such as git.Repository, secret .etc.
 */
 
-func saveAndPush(gitRepo *git.Repository, user, targetFile string, data []byte, secret *v1.Secret, commitMessage string) (err error) {
+func saveAndPush(gitRepo *git.Repository, identity gitIdentity, targetFile string, data []byte, secret *v1.Secret, commitMessage string) (err error) {
+	if err = saveAndCommit(gitRepo, identity, targetFile, data, commitMessage); err == nil {
+		err = pushTags(gitRepo, "", getAuth(secret))
+	}
+	return
+}
+
+// saveAndCommit writes the data into the file, then commits it without pushing
+func saveAndCommit(gitRepo *git.Repository, identity gitIdentity, targetFile string, data []byte, commitMessage string) (err error) {
 	if err = ioutil.WriteFile(targetFile, data, 0644); err != nil {
 		fmt.Println("failed to write file", targetFile)
 	} else {
-		if err = addAndCommit(gitRepo, user, commitMessage); err == nil {
-			err = pushTags(gitRepo, "", getAuth(secret))
-		}
+		err = addAndCommit(gitRepo, identity, commitMessage)
 	}
 	return
 }
 
-func addAndCommit(repo *git.Repository, user, commitMessage string) (err error) {
+func addAndCommit(repo *git.Repository, identity gitIdentity, commitMessage string) (err error) {
 	var w *git.Worktree
 	if w, err = repo.Worktree(); err == nil {
 		_, _ = w.Add(".")
 		var commit plumbing.Hash
 		commit, err = w.Commit(commitMessage, &git.CommitOptions{
-			All: true,
-			Author: &object.Signature{
-				Name:  user,
-				Email: fmt.Sprintf("%s@users.noreply.github.com", user),
-				When:  time.Now(),
-			},
+			All:     true,
+			Author:  identity.signature(),
+			SignKey: identity.signKey,
 		})
 
 		if err == nil {
@@ -60,18 +63,68 @@ func addAndCommit(repo *git.Repository, user, commitMessage string) (err error)
 }
 
 func getGitProviderClient(repo devopsv1alpha1.Repository, secret *v1.Secret) internal_scm.GitReleaser {
+	username := string(secret.Data[v1.BasicAuthUsernameKey])
 	token := string(secret.Data[v1.BasicAuthPasswordKey])
 	server := string(secret.Data["server"])
 	orgAndRepo := getOrgAndRepo(repo, server)
 
-	return internal_scm.GetGitProvider(string(repo.Provider), server, orgAndRepo, token)
+	return internal_scm.GetGitProvider(string(repo.Provider), server, orgAndRepo, username, token)
+}
+
+// releaseOption holds the shared options of releasing the repositories of a Releaser
+type releaseOption struct {
+	secret   *v1.Secret
+	identity gitIdentity
+	cacheDir string
+	// createBranch indicates to cut the release branch before tagging
+	createBranch bool
+	// changelogFile is the file to record the release notes before tagging, it is disabled if it is empty
+	changelogFile string
+	// releaseNotesTemplate is the template of the release notes, the default one will be used if it is empty
+	releaseNotesTemplate string
+	scheme               versioning.Scheme
+}
+
+// release creates the tag (and the release if necessary) of a repository, the result will be recorded into the status
+func release(repo devopsv1alpha1.Repository, option releaseOption, status *devopsv1alpha1.RepositoryStatus) (err error) {
+	// the tag was pushed by a previous attempt, no need to do it again
+	if status.Commit == "" {
+		if err = tagRepository(repo, option, status); err != nil {
+			return
+		}
+	}
+
+	provider := getGitProviderClient(repo, option.secret)
+	if provider == nil {
+		return
+	}
+
+	action := getAction(repo, option.scheme)
+	switch action {
+	case devopsv1alpha1.ActionPreRelease, devopsv1alpha1.ActionRelease:
+		var notes string
+		if notes, err = generateReleaseNotes(repo, option); err != nil {
+			err = fmt.Errorf("failed to generate the release notes of %s, error: %v", repo.Address, err)
+			return
+		}
+		status.ReleaseURL, err = provider.Release(repo.Version, repo.Branch, notes, false,
+			action == devopsv1alpha1.ActionPreRelease)
+	}
+	return
 }
 
-func release(repo devopsv1alpha1.Repository, secret *v1.Secret, user string) (err error) {
-	auth := getAuth(secret)
+// tagRepository creates and pushes the tag (and the release branch if necessary) of a repository,
+// records the tagged commit into the status
+func tagRepository(repo devopsv1alpha1.Repository, option releaseOption, status *devopsv1alpha1.RepositoryStatus) (err error) {
+	auth := getAuth(option.secret)
+	option.identity = option.identity.forRepository(repo)
+
+	// avoid operating the same local repository at the same time
+	unlock := lockRepoCacheDir(option.cacheDir, repo.Address)
+	defer unlock()
 
 	var gitRepo *git.Repository
-	if gitRepo, err = clone(repo.Address, repo.Branch, auth, "tmp"); err != nil {
+	if gitRepo, err = clone(repo.Address, repo.Branch, auth, option.cacheDir); err != nil {
 		err = fmt.Errorf("failed to clone %s, error: %v", repo.Address, err)
 		return
 	}
@@ -79,28 +132,67 @@ func release(repo devopsv1alpha1.Repository, secret *v1.Secret, user string) (er
 	if repo.Message == "" {
 		repo.Message = "released by ks-releaser"
 	}
-	if _, err = setTag(gitRepo, repo.Version, repo.Message, user); err != nil {
+	var target plumbing.Hash
+	if target, err = getTagTarget(gitRepo, repo.Commit, repo.Branch); err != nil {
+		err = fmt.Errorf("failed to find the commit to tag from %s, error: %v", repo.Address, err)
+		return
+	}
+
+	if len(repo.VersionFiles) > 0 {
+		if target, err = updateVersionFiles(gitRepo, repo, option, target); err != nil {
+			err = fmt.Errorf("failed to update the version files of %s, error: %v", repo.Address, err)
+			return
+		}
+	}
+
+	if option.changelogFile != "" {
+		if target, err = updateChangelog(gitRepo, repo, option, target); err != nil {
+			err = fmt.Errorf("failed to update the changelog of %s, error: %v", repo.Address, err)
+			return
+		}
+	}
+
+	if option.createBranch {
+		var branch string
+		if branch, err = getReleaseBranchName(option.scheme, repo.Version); err != nil {
+			return
+		}
+
+		var created bool
+		if created, err = createBranch(gitRepo, branch, target); err != nil {
+			err = fmt.Errorf("failed to create branch %s for %s, error: %v", branch, repo.Address, err)
+			return
+		}
+		if created {
+			if err = pushBranch(gitRepo, branch, auth); err != nil {
+				err = fmt.Errorf("failed to push branch %s into %s, error: %v", branch, repo.Address, err)
+				return
+			}
+		}
+		status.ReleaseBranch = branch
+	}
+
+	var created bool
+	if created, err = setTag(gitRepo, repo.Version, repo.Message, option.identity, target, repo.OnExistingTag); err != nil {
 		err = fmt.Errorf("failed to create tag %s for %s, error: %v", repo.Version, repo.Address, err)
 		return
 	}
 
 	if err = pushTags(gitRepo, repo.Version, auth); err != nil {
+		if created {
+			// the local tag should not be treated as an existing one in the next attempt
+			_ = gitRepo.DeleteTag(repo.Version)
+		}
 		err = fmt.Errorf("failed to push tag %s into %s, error: %v", repo.Version, repo.Address, err)
 		return
 	}
 
-	provider := getGitProviderClient(repo, secret)
-	if provider == nil {
+	var commit plumbing.Hash
+	if commit, err = getTagCommit(gitRepo, repo.Version); err != nil {
+		err = fmt.Errorf("failed to find the commit of tag %s from %s, error: %v", repo.Version, repo.Address, err)
 		return
 	}
-
-	action := getAction(repo)
-	switch action {
-	case devopsv1alpha1.ActionPreRelease:
-		err = provider.Release(repo.Version, repo.Branch, false, true)
-	case devopsv1alpha1.ActionRelease:
-		err = provider.Release(repo.Version, repo.Branch, false, false)
-	}
+	status.Commit = commit.String()
 	return
 }
 
@@ -111,22 +203,31 @@ func getOrgAndRepo(repo devopsv1alpha1.Repository, server string) (orgAndRepo st
 
 	switch provider {
 	case devopsv1alpha1.ProviderGitHub:
-		orgAndRepo = strings.ReplaceAll(address, "https://github.com/", "")
+		orgAndRepo = trimServer(address, server, "https://github.com/")
 	case devopsv1alpha1.ProviderGitlab:
-		orgAndRepo = strings.ReplaceAll(address, "https://gitlab.com/", "")
+		orgAndRepo = trimServer(address, server, "https://gitlab.com/")
 	case devopsv1alpha1.ProviderGitee:
-		orgAndRepo = strings.ReplaceAll(address, "https://gitee.com/", "")
+		orgAndRepo = trimServer(address, server, "https://gitee.com/")
 	case devopsv1alpha1.ProviderBitbucket:
-		orgAndRepo = strings.ReplaceAll(address, "https://bitbucket.org/", "")
+		// the clone address of Bitbucket Server is like: https://host/scm/project/repo.git
+		orgAndRepo = strings.TrimPrefix(trimServer(address, server, "https://bitbucket.org/"), "scm/")
 	case devopsv1alpha1.ProviderGitea:
 		orgAndRepo = strings.ReplaceAll(address, server, "")
 	}
 	return
 }
 
-func getAction(repo devopsv1alpha1.Repository) (action devopsv1alpha1.Action) {
+// trimServer removes the self-hosted server (or the default one) from a git address
+func trimServer(address, server, defaultServer string) string {
+	if server = strings.TrimSuffix(server, "/"); server != "" && strings.HasPrefix(address, server+"/") {
+		return strings.TrimPrefix(address, server+"/")
+	}
+	return strings.ReplaceAll(address, defaultServer, "")
+}
+
+func getAction(repo devopsv1alpha1.Repository, scheme versioning.Scheme) (action devopsv1alpha1.Action) {
 	action = repo.Action
-	if action == devopsv1alpha1.ActionAuto && isPreRelease(repo.Version) {
+	if action == devopsv1alpha1.ActionAuto && isPreRelease(scheme, repo.Version) {
 		action = devopsv1alpha1.ActionPreRelease
 	}
 	return
@@ -151,12 +252,11 @@ func getAuth(secret *v1.Secret) (auth transport.AuthMethod) {
 }
 
 func clone(gitRepo, branch string, auth transport.AuthMethod, cacheDir string) (repo *git.Repository, err error) {
-	var gitRepoURL *url.URL
-	if gitRepoURL, err = url.Parse(gitRepo); err != nil {
+	var dir string
+	if dir, err = getRepoCacheDir(cacheDir, gitRepo); err != nil {
 		return
 	}
 
-	dir := path.Join(cacheDir, gitRepoURL.Path)
 	if ok, _ := PathExists(dir); ok {
 		if repo, err = git.PlainOpen(dir); err == nil {
 			var wd *git.Worktree
@@ -221,42 +321,72 @@ func clone(gitRepo, branch string, auth transport.AuthMethod, cacheDir string) (
 	return
 }
 
-func remoteTagExists(tag string, r *git.Repository) bool {
-	var err error
-	var tags storer.ReferenceIter
-
-	if tags, err = r.Tags(); err == nil {
-		var ref *plumbing.Reference
-		for ref, _ = tags.Next(); ref != nil; ref, _ = tags.Next() {
-			if ref.Name().IsTag() && ref.Name().Short() == tag {
-				if _, err = r.TagObject(ref.Hash()); err == nil {
-					return true
-				}
-				break
-			}
-		}
+// getRepoCacheDir returns the local directory of a git repository, each repository has its own one
+func getRepoCacheDir(cacheDir, address string) (dir string, err error) {
+	var gitRepoURL *url.URL
+	if gitRepoURL, err = url.Parse(address); err == nil {
+		dir = path.Join(cacheDir, gitRepoURL.Host, gitRepoURL.Path)
 	}
-	return false
+	return
 }
 
-func setTag(r *git.Repository, tag, message, user string) (bool, error) {
-	if remoteTagExists(tag, r) {
-		fmt.Printf("tag %s already exists\n", tag)
-		return false, nil
+// repoCacheDirLocks holds the locks of the local git repositories
+var repoCacheDirLocks sync.Map
+
+// lockRepoCacheDir locks the local directory of a git repository, returns the unlock function
+func lockRepoCacheDir(cacheDir, address string) (unlock func()) {
+	dir, _ := getRepoCacheDir(cacheDir, address)
+	lock, _ := repoCacheDirLocks.LoadOrStore(dir, &sync.Mutex{})
+	mutex := lock.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// getTagTarget returns the commit which a new tag would point to.
+// It is the head of the branch if the commit is empty, or the commit must be reachable from the branch.
+func getTagTarget(r *git.Repository, commit, branch string) (hash plumbing.Hash, err error) {
+	var head *plumbing.Reference
+	if head, err = r.Head(); err != nil {
+		return
 	}
-	fmt.Printf("Set tag %s\n", tag)
-	h, err := r.Head()
-	if err != nil {
-		fmt.Printf("get HEAD error: %s\n", err)
+	if commit == "" {
+		hash = head.Hash()
+		return
+	}
+
+	var target *plumbing.Hash
+	if target, err = r.ResolveRevision(plumbing.Revision(commit)); err != nil {
+		err = fmt.Errorf("cannot find commit %s, error: %v", commit, err)
+		return
+	}
+
+	var targetCommit, headCommit *object.Commit
+	if targetCommit, err = r.CommitObject(*target); err != nil {
+		return
+	}
+	if headCommit, err = r.CommitObject(head.Hash()); err != nil {
+		return
+	}
+
+	var reachable bool
+	if reachable, err = targetCommit.IsAncestor(headCommit); err == nil && !reachable {
+		err = fmt.Errorf("commit %s is not reachable from branch %s", commit, branch)
+	} else if err == nil {
+		hash = *target
+	}
+	return
+}
+
+func setTag(r *git.Repository, tag, message string, identity gitIdentity, target plumbing.Hash,
+	onExistingTag devopsv1alpha1.OnExistingTag) (bool, error) {
+	if exists, err := checkExistingTag(r, tag, target, onExistingTag); err != nil || exists {
 		return false, err
 	}
-	_, err = r.CreateTag(tag, h.Hash(), &git.CreateTagOptions{
-		Tagger: &object.Signature{
-			Name:  user,
-			Email: fmt.Sprintf("%s@users.noreply.github.com", user),
-			When:  time.Now(),
-		},
+	fmt.Printf("Set tag %s\n", tag)
+	_, err := r.CreateTag(tag, target, &git.CreateTagOptions{
+		Tagger:  identity.signature(),
 		Message: message,
+		SignKey: identity.signKey,
 	})
 
 	if err != nil {
@@ -266,12 +396,75 @@ func setTag(r *git.Repository, tag, message, user string) (bool, error) {
 	return true, nil
 }
 
+// checkExistingTag checks the existing tag with the policy, the default policy is skip
+func checkExistingTag(r *git.Repository, tag string, target plumbing.Hash,
+	onExistingTag devopsv1alpha1.OnExistingTag) (exists bool, err error) {
+	var existing plumbing.Hash
+	if existing, err = getTagCommit(r, tag); err == git.ErrTagNotFound {
+		err = nil
+		return
+	} else if err != nil {
+		return
+	}
+
+	exists = true
+	switch onExistingTag {
+	case devopsv1alpha1.OnExistingTagFail:
+		err = fmt.Errorf("tag %s already exists", tag)
+	case devopsv1alpha1.OnExistingTagVerify:
+		if existing != target {
+			err = fmt.Errorf("tag %s already exists, but it points to %s instead of %s",
+				tag, existing.String(), target.String())
+		}
+	default:
+		fmt.Printf("tag %s already exists\n", tag)
+	}
+	return
+}
+
+// getTagCommit returns the commit which the tag points to
+func getTagCommit(r *git.Repository, tag string) (hash plumbing.Hash, err error) {
+	var ref *plumbing.Reference
+	if ref, err = r.Tag(tag); err != nil {
+		return
+	}
+
+	var tagObj *object.Tag
+	if tagObj, err = r.TagObject(ref.Hash()); err == nil {
+		var commit *object.Commit
+		if commit, err = tagObj.Commit(); err == nil {
+			hash = commit.Hash
+		}
+	} else if err == plumbing.ErrObjectNotFound {
+		// it is a lightweight tag
+		hash, err = ref.Hash(), nil
+	}
+	return
+}
+
 func pushTags(r *git.Repository, tag string, auth transport.AuthMethod) (err error) {
 	var ref []config.RefSpec
 	if tag != "" {
 		ref = []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/tags/%s:refs/tags/%s", tag, tag))}
 	}
+	err = pushRefSpecs(r, ref, auth)
+	return
+}
+
+func pushBranch(r *git.Repository, branch string, auth transport.AuthMethod) (err error) {
+	ref := []config.RefSpec{config.RefSpec(fmt.Sprintf("refs/heads/%s:refs/heads/%s", branch, branch))}
+	err = pushRefSpecs(r, ref, auth)
+	return
+}
+
+// forcePushBranch pushes the branch even if the remote one is not an ancestor of it
+func forcePushBranch(r *git.Repository, branch string, auth transport.AuthMethod) (err error) {
+	ref := []config.RefSpec{config.RefSpec(fmt.Sprintf("+refs/heads/%s:refs/heads/%s", branch, branch))}
+	err = pushRefSpecs(r, ref, auth)
+	return
+}
 
+func pushRefSpecs(r *git.Repository, ref []config.RefSpec, auth transport.AuthMethod) (err error) {
 	po := &git.PushOptions{
 		RemoteName: "origin",
 		Progress:   os.Stdout,
@@ -289,6 +482,55 @@ func pushTags(r *git.Repository, tag string, auth transport.AuthMethod) (err err
 	return
 }
 
+// createBranch creates a local branch on the target commit if the remote one does not exist.
+// It returns false if the existing remote branch contains the target commit already.
+func createBranch(r *git.Repository, branch string, target plumbing.Hash) (created bool, err error) {
+	var remoteRef *plumbing.Reference
+	if remoteRef, err = r.Reference(plumbing.NewRemoteReferenceName("origin", branch), true); err == nil {
+		var targetCommit, branchCommit *object.Commit
+		if targetCommit, err = r.CommitObject(target); err != nil {
+			return
+		}
+		if branchCommit, err = r.CommitObject(remoteRef.Hash()); err != nil {
+			return
+		}
+
+		var reachable bool
+		if reachable, err = targetCommit.IsAncestor(branchCommit); err == nil && !reachable {
+			err = fmt.Errorf("branch %s exists already, but it does not contain commit %s", branch, target.String())
+		}
+		return
+	} else if err != plumbing.ErrReferenceNotFound {
+		return
+	}
+
+	err = r.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(branch), target))
+	created = err == nil
+	return
+}
+
+// checkoutNewBranch points the branch to the head, then checks it out. The existing branch will be reset.
+func checkoutNewBranch(r *git.Repository, branch string) (err error) {
+	var head *plumbing.Reference
+	if head, err = r.Head(); err != nil {
+		return
+	}
+
+	ref := plumbing.NewBranchReferenceName(branch)
+	if err = r.Storer.SetReference(plumbing.NewHashReference(ref, head.Hash())); err != nil {
+		return
+	}
+
+	var wd *git.Worktree
+	if wd, err = r.Worktree(); err == nil {
+		err = wd.Checkout(&git.CheckoutOptions{
+			Branch: ref,
+			Force:  true,
+		})
+	}
+	return
+}
+
 // PathExists checks if the target path exist or not
 func PathExists(path string) (bool, error) {
 	_, err := os.Stat(path)
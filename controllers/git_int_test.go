@@ -3,6 +3,7 @@
 package controllers
 
 import (
+	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
 	"math/rand"
 	"strconv"
@@ -10,29 +11,36 @@ import (
 	"time"
 )
 
-func TestRemoteTagExists(t *testing.T) {
+func TestCheckExistingTag(t *testing.T) {
 	repo, err := clone("https://gitee.com/linuxsuren/test", "master", nil, "bin/tmp")
 	assert.Nil(t, err)
 
-	result := remoteTagExists("v0.0.7", repo)
-	assert.True(t, result)
+	target, err := getTagTarget(repo, "", "master")
+	assert.Nil(t, err)
 
-	result = remoteTagExists("v0.0.8", repo)
-	assert.True(t, result)
+	exists, err := checkExistingTag(repo, "v0.0.7", target, "")
+	assert.True(t, exists)
+	assert.Nil(t, err)
+
+	exists, err = checkExistingTag(repo, "v0.0.8", target, v1alpha1.OnExistingTagFail)
+	assert.True(t, exists)
+	assert.NotNil(t, err)
 
 	def := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	// not existing
 	fakeTag := strconv.Itoa(def.Int())
-	result = remoteTagExists(fakeTag, repo)
-	assert.False(t, result)
+	exists, err = checkExistingTag(repo, fakeTag, target, v1alpha1.OnExistingTagFail)
+	assert.False(t, exists)
+	assert.Nil(t, err)
 
-	result, err = setTag(repo, fakeTag, "message", "user")
+	result, err := setTag(repo, fakeTag, "message", newGitIdentity("user"), target, "")
 	assert.True(t, result)
 	assert.Nil(t, err)
 
-	result = remoteTagExists(fakeTag, repo)
-	assert.True(t, result)
+	exists, err = checkExistingTag(repo, fakeTag, target, v1alpha1.OnExistingTagVerify)
+	assert.True(t, exists)
+	assert.Nil(t, err)
 
 	err = pushTags(repo, "xx", nil)
 	assert.NotNil(t, err)
@@ -1,12 +1,20 @@
 package controllers
 
 import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/go-git/go-git/v5/plumbing/transport"
 	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	v1 "k8s.io/api/core/v1"
+	"path"
+	"strconv"
 	"testing"
+	"time"
 )
 
 func TestGetAuth(t *testing.T) {
@@ -91,7 +99,7 @@ func Test_getAction(t *testing.T) {
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if gotAction := getAction(tt.args.repo); gotAction != tt.wantAction {
+			if gotAction := getAction(tt.args.repo, versioning.NewSemVer()); gotAction != tt.wantAction {
 				t.Errorf("getAction() = %v, want %v", gotAction, tt.wantAction)
 			}
 		})
@@ -147,6 +155,36 @@ func Test_getOrgAndRepo(t *testing.T) {
 			},
 		},
 		wantOrgAndRepo: "x/b",
+	}, {
+		name: "github enterprise",
+		args: args{
+			repo: v1alpha1.Repository{
+				Provider: v1alpha1.ProviderGitHub,
+				Address:  "https://github.example.com/x/b.git",
+			},
+			server: "https://github.example.com",
+		},
+		wantOrgAndRepo: "x/b",
+	}, {
+		name: "self-managed gitlab with sub groups",
+		args: args{
+			repo: v1alpha1.Repository{
+				Provider: v1alpha1.ProviderGitlab,
+				Address:  "https://gitlab.example.com/x/y/b.git",
+			},
+			server: "https://gitlab.example.com/",
+		},
+		wantOrgAndRepo: "x/y/b",
+	}, {
+		name: "bitbucket server",
+		args: args{
+			repo: v1alpha1.Repository{
+				Provider: v1alpha1.ProviderBitbucket,
+				Address:  "https://bitbucket.example.com/scm/proj/b.git",
+			},
+			server: "https://bitbucket.example.com/",
+		},
+		wantOrgAndRepo: "proj/b",
 	}, {
 		name: "gitea with git protocol",
 		args: args{
@@ -166,3 +204,177 @@ func Test_getOrgAndRepo(t *testing.T) {
 		})
 	}
 }
+
+func Test_getRepoCacheDir(t *testing.T) {
+	dir, err := getRepoCacheDir("tmp", "https://github.com/x/b")
+	assert.Nil(t, err)
+	assert.Equal(t, "tmp/github.com/x/b", dir)
+
+	// the same path from different hosts
+	dir, err = getRepoCacheDir("tmp", "https://gitee.com/x/b")
+	assert.Nil(t, err)
+	assert.Equal(t, "tmp/gitee.com/x/b", dir)
+
+	_, err = getRepoCacheDir("tmp", "xx://wrong url format")
+	assert.NotNil(t, err)
+}
+
+// newLocalRepository creates a git repository which has the given commits in a temporary directory
+func newLocalRepository(t *testing.T, messages ...string) (dir string, repo *git.Repository) {
+	dir = t.TempDir()
+	repo, err := git.PlainInit(dir, false)
+	assert.Nil(t, err)
+
+	var wd *git.Worktree
+	wd, err = repo.Worktree()
+	assert.Nil(t, err)
+	for i, message := range messages {
+		err = ioutil.WriteFile(path.Join(dir, "README.md"), []byte(strconv.Itoa(i)), 0644)
+		assert.Nil(t, err)
+		_, err = wd.Add("README.md")
+		assert.Nil(t, err)
+		_, err = wd.Commit(message, &git.CommitOptions{
+			Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		})
+		assert.Nil(t, err)
+	}
+	return
+}
+
+func Test_getTagTarget(t *testing.T) {
+	_, repo := newLocalRepository(t, "first commit", "second commit")
+	head, err := repo.Head()
+	assert.Nil(t, err)
+	headCommit, err := repo.CommitObject(head.Hash())
+	assert.Nil(t, err)
+	firstCommit := headCommit.ParentHashes[0]
+
+	// create a commit which is not reachable from master
+	wd, err := repo.Worktree()
+	assert.Nil(t, err)
+	err = wd.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("other"), Create: true})
+	assert.Nil(t, err)
+	otherCommit, err := wd.Commit("other commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	assert.Nil(t, err)
+	err = wd.Checkout(&git.CheckoutOptions{Branch: plumbing.NewBranchReferenceName("master")})
+	assert.Nil(t, err)
+
+	// the head of the branch
+	target, err := getTagTarget(repo, "", "master")
+	assert.Nil(t, err)
+	assert.Equal(t, head.Hash(), target)
+
+	// a reachable commit
+	target, err = getTagTarget(repo, firstCommit.String(), "master")
+	assert.Nil(t, err)
+	assert.Equal(t, firstCommit, target)
+
+	// a reference
+	target, err = getTagTarget(repo, "HEAD~1", "master")
+	assert.Nil(t, err)
+	assert.Equal(t, firstCommit, target)
+
+	// not reachable from the branch
+	_, err = getTagTarget(repo, otherCommit.String(), "master")
+	assert.NotNil(t, err)
+
+	// not existing
+	_, err = getTagTarget(repo, "fake", "master")
+	assert.NotNil(t, err)
+}
+
+func Test_tagRepository(t *testing.T) {
+	dir, remote := newLocalRepository(t, "first commit")
+	head, err := remote.Head()
+	assert.Nil(t, err)
+
+	repo := v1alpha1.Repository{
+		Address: dir,
+		Branch:  "master",
+		Version: "v1.2.0",
+	}
+	status := &v1alpha1.RepositoryStatus{}
+	err = tagRepository(repo, releaseOption{identity: newGitIdentity("user"), cacheDir: t.TempDir(), createBranch: true, scheme: versioning.NewSemVer()}, status)
+	assert.Nil(t, err)
+	assert.Equal(t, head.Hash().String(), status.Commit)
+	assert.Equal(t, "release-1.2", status.ReleaseBranch)
+
+	// the tag and the release branch were pushed
+	tagCommit, err := getTagCommit(remote, "v1.2.0")
+	assert.Nil(t, err)
+	assert.Equal(t, head.Hash(), tagCommit)
+	branch, err := remote.Reference(plumbing.NewBranchReferenceName("release-1.2"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, head.Hash(), branch.Hash())
+
+	// release a patch version from the existing release branch
+	repo.Branch = "release-1.2"
+	repo.Version = "v1.2.1"
+	status = &v1alpha1.RepositoryStatus{}
+	err = tagRepository(repo, releaseOption{identity: newGitIdentity("user"), cacheDir: t.TempDir(), createBranch: true, scheme: versioning.NewSemVer()}, status)
+	assert.Nil(t, err)
+	assert.Equal(t, "release-1.2", status.ReleaseBranch)
+
+	// cannot tag an invalid commit
+	repo.Commit = "fake"
+	err = tagRepository(repo, releaseOption{identity: newGitIdentity("user"), cacheDir: t.TempDir()}, &v1alpha1.RepositoryStatus{})
+	assert.NotNil(t, err)
+}
+
+func Test_checkExistingTag(t *testing.T) {
+	_, repo := newLocalRepository(t, "first commit", "second commit")
+	head, err := repo.Head()
+	assert.Nil(t, err)
+	headCommit, err := repo.CommitObject(head.Hash())
+	assert.Nil(t, err)
+	_, err = repo.CreateTag("v0.0.1", head.Hash(), nil)
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name          string
+		tag           string
+		target        plumbing.Hash
+		onExistingTag v1alpha1.OnExistingTag
+		wantExists    bool
+		wantErr       bool
+	}{{
+		name:          "tag does not exist",
+		tag:           "v0.0.2",
+		target:        head.Hash(),
+		onExistingTag: v1alpha1.OnExistingTagFail,
+	}, {
+		name:       "skip by default",
+		tag:        "v0.0.1",
+		target:     headCommit.ParentHashes[0],
+		wantExists: true,
+	}, {
+		name:          "fail",
+		tag:           "v0.0.1",
+		target:        head.Hash(),
+		onExistingTag: v1alpha1.OnExistingTagFail,
+		wantExists:    true,
+		wantErr:       true,
+	}, {
+		name:          "verify the same commit",
+		tag:           "v0.0.1",
+		target:        head.Hash(),
+		onExistingTag: v1alpha1.OnExistingTagVerify,
+		wantExists:    true,
+	}, {
+		name:          "verify another commit",
+		tag:           "v0.0.1",
+		target:        headCommit.ParentHashes[0],
+		onExistingTag: v1alpha1.OnExistingTagVerify,
+		wantExists:    true,
+		wantErr:       true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exists, err := checkExistingTag(repo, tt.tag, tt.target, tt.onExistingTag)
+			assert.Equal(t, tt.wantExists, exists)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+		})
+	}
+}
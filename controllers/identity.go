@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	"time"
+)
+
+const (
+	// nameSecretKey and emailSecretKey are the keys of the optional author identity
+	nameSecretKey  = "name"
+	emailSecretKey = "email"
+	// defaultGitUser is the name of the author when there is no username in the secret
+	defaultGitUser = "ks-releaser"
+)
+
+// gitIdentity is the author of the commits and the tagger of the tags, they will be signed if the key exists
+type gitIdentity struct {
+	// user is the username of the git secret, the default email comes from it
+	user    string
+	name    string
+	email   string
+	signKey *openpgp.Entity
+}
+
+// newGitIdentity creates the identity of a git user without signing
+func newGitIdentity(user string) gitIdentity {
+	if user == "" {
+		user = defaultGitUser
+	}
+	return gitIdentity{
+		user: user,
+		name: user,
+	}
+}
+
+// signature returns the signature of the identity at the current time
+func (i gitIdentity) signature() *object.Signature {
+	return &object.Signature{
+		Name:  i.name,
+		Email: i.email,
+		When:  time.Now(),
+	}
+}
+
+// withAuthor overrides the name and email if they are not empty
+func (i gitIdentity) withAuthor(name, email string) gitIdentity {
+	if name != "" {
+		i.name = name
+	}
+	if email != "" {
+		i.email = email
+	}
+	return i
+}
+
+// withSecret overrides the name and email by the keys of the secret
+func (i gitIdentity) withSecret(secret *v1.Secret) gitIdentity {
+	if secret == nil {
+		return i
+	}
+	return i.withAuthor(string(secret.Data[nameSecretKey]), string(secret.Data[emailSecretKey]))
+}
+
+// forRepository fills the email with the noreply address of the git provider if it is not set
+func (i gitIdentity) forRepository(repo devopsv1alpha1.Repository) gitIdentity {
+	if i.email == "" {
+		i.email = getNoReplyEmail(i.user, repo)
+	}
+	return i
+}
+
+// getNoReplyEmail returns the noreply email of a git user, such as: linuxsuren@users.noreply.github.com
+func getNoReplyEmail(user string, repo devopsv1alpha1.Repository) string {
+	host := devopsv1alpha1.GetHost(repo.Address)
+	switch devopsv1alpha1.GetDefaultProvider(&repo) {
+	case devopsv1alpha1.ProviderGitHub:
+		// GitHub Enterprise has the same noreply sub-domain
+		if host == "" {
+			host = "github.com"
+		}
+		return fmt.Sprintf("%s@users.noreply.%s", user, host)
+	case devopsv1alpha1.ProviderGitlab:
+		// the self-managed GitLab has the same noreply sub-domain by default
+		if host == "" {
+			host = "gitlab.com"
+		}
+		return fmt.Sprintf("%s@users.noreply.%s", user, host)
+	case devopsv1alpha1.ProviderGitee:
+		return fmt.Sprintf("%s@user.noreply.gitee.com", user)
+	}
+
+	// Gitea and the self-hosted ones use the noreply sub-domain by default
+	if host != "" {
+		return fmt.Sprintf("%s@noreply.%s", user, host)
+	}
+	return fmt.Sprintf("%s@noreply.localhost", user)
+}
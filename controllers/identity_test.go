@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_getNoReplyEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		repo v1alpha1.Repository
+		want string
+	}{{
+		name: "github",
+		repo: v1alpha1.Repository{Address: "https://github.com/linuxsuren/test"},
+		want: "bot@users.noreply.github.com",
+	}, {
+		name: "gitlab",
+		repo: v1alpha1.Repository{Address: "https://gitlab.com/linuxsuren/test"},
+		want: "bot@users.noreply.gitlab.com",
+	}, {
+		name: "gitee",
+		repo: v1alpha1.Repository{Address: "https://gitee.com/linuxsuren/test"},
+		want: "bot@user.noreply.gitee.com",
+	}, {
+		name: "gitea",
+		repo: v1alpha1.Repository{Address: "https://gitea.com/linuxsuren/test"},
+		want: "bot@noreply.gitea.com",
+	}, {
+		name: "github enterprise",
+		repo: v1alpha1.Repository{Address: "https://github.example.com/linuxsuren/test", Provider: v1alpha1.ProviderGitHub},
+		want: "bot@users.noreply.github.example.com",
+	}, {
+		name: "self-managed gitlab",
+		repo: v1alpha1.Repository{Address: "https://gitlab.example.com/linuxsuren/test", Provider: v1alpha1.ProviderGitlab},
+		want: "bot@users.noreply.gitlab.example.com",
+	}, {
+		name: "self-hosted gitea",
+		repo: v1alpha1.Repository{Address: "http://git.example.com:3000/linuxsuren/test", Provider: v1alpha1.ProviderGitea},
+		want: "bot@noreply.git.example.com",
+	}, {
+		name: "ssh address",
+		repo: v1alpha1.Repository{Address: "git@git.example.com:linuxsuren/test.git"},
+		want: "bot@noreply.git.example.com",
+	}, {
+		name: "local path",
+		repo: v1alpha1.Repository{Address: "/tmp/test"},
+		want: "bot@noreply.localhost",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, getNoReplyEmail("bot", tt.repo))
+		})
+	}
+}
+
+func Test_gitIdentity_forRepository(t *testing.T) {
+	repo := v1alpha1.Repository{Address: "https://gitea.com/linuxsuren/test"}
+
+	identity := newGitIdentity("").forRepository(repo)
+	assert.Equal(t, defaultGitUser, identity.name)
+	assert.Equal(t, "ks-releaser@noreply.gitea.com", identity.email)
+
+	// keep the configured email
+	identity = newGitIdentity("bot").withAuthor("", "bot@example.com").forRepository(repo)
+	assert.Equal(t, "bot", identity.name)
+	assert.Equal(t, "bot@example.com", identity.email)
+}
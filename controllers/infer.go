@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"time"
+)
+
+// inferVersionsInterval is the interval of checking the new commits of a draft Releaser
+const inferVersionsInterval = 10 * time.Minute
+
+// needToInferVersions checks if the versions of a draft Releaser should be inferred from the commits
+func needToInferVersions(releaser *devopsv1alpha1.Releaser) bool {
+	nextVersion := releaser.Spec.NextVersion
+	return releaser.Spec.Phase == devopsv1alpha1.PhaseDraft && nextVersion != nil && nextVersion.InferFromCommits
+}
+
+// inferVersion infers the next version from the previous one and the conventional commits since it.
+// A breaking change bumps the major number, a feature bumps the minor number, others bump the patch number.
+// The versioning scheme decides how to bump the numbers.
+func inferVersion(scheme versioning.Scheme, previousVersion string, commits []releaseCommit) (nextVersion string, err error) {
+	nextVersion = previousVersion
+
+	if err = scheme.Validate(previousVersion); err != nil {
+		err = fmt.Errorf("cannot infer from an invalid version: %s, error: %v", previousVersion, err)
+		return
+	}
+	if len(commits) == 0 {
+		return
+	}
+
+	level := versioning.LevelPatch
+	for _, commit := range commits {
+		if commit.Breaking {
+			level = versioning.LevelMajor
+			break
+		}
+		if commit.Type == "feat" {
+			level = versioning.LevelMinor
+		}
+	}
+	nextVersion, err = scheme.Increase(previousVersion, level)
+	return
+}
+
+// inferRepositoryVersion infers the next version of a repository from the commits since the last tag which is valid
+// in the versioning scheme, the current version is kept if there is no such tag or new commit
+func inferRepositoryVersion(repo devopsv1alpha1.Repository, option releaseOption) (version string, err error) {
+	version = repo.Version
+
+	unlock := lockRepoCacheDir(option.cacheDir, repo.Address)
+	defer unlock()
+
+	var gitRepo *git.Repository
+	if gitRepo, err = clone(repo.Address, repo.Branch, getAuth(option.secret), option.cacheDir); err != nil {
+		err = fmt.Errorf("failed to clone %s, error: %v", repo.Address, err)
+		return
+	}
+
+	var head *plumbing.Reference
+	if head, err = gitRepo.Head(); err != nil {
+		return
+	}
+
+	var commits []releaseCommit
+	var previousTag string
+	if commits, previousTag, err = getCommitsSinceLastTag(gitRepo, head.Hash(), "", option.scheme); err != nil {
+		err = fmt.Errorf("failed to find the commits of %s, error: %v", repo.Address, err)
+		return
+	}
+	if previousTag == "" || len(commits) == 0 {
+		return
+	}
+	version, err = inferVersion(option.scheme, previousTag, commits)
+	return
+}
+
+// inferVersions infers the versions of the repositories and the Releaser, returns true if any of them was changed.
+// The pre-release versions are kept as they are.
+func (r *ReleaserReconciler) inferVersions(ctx context.Context, releaser *devopsv1alpha1.Releaser) (changed bool, err error) {
+	secret := &v1.Secret{}
+	if err = r.Get(ctx, types.NamespacedName{
+		Namespace: releaser.Spec.Secret.Namespace,
+		Name:      releaser.Spec.Secret.Name,
+	}, secret); err != nil {
+		return
+	}
+
+	option := r.newReleaseOption(releaser, secret)
+	repos := releaser.Spec.Repositories
+	versions := make([]string, len(repos))
+	inferErrs := make([]error, len(repos))
+	runConcurrently(len(repos), r.getConcurrency(releaser), func(index int) {
+		if isPreRelease(option.scheme, repos[index].Version) {
+			versions[index] = repos[index].Version
+			return
+		}
+		versions[index], inferErrs[index] = inferRepositoryVersion(repos[index], option)
+	})
+
+	var errSlice ErrorSlice
+	for i, version := range versions {
+		if inferErrs[i] != nil {
+			errSlice = errSlice.append(inferErrs[i])
+		} else if repos[i].Version != version {
+			repos[i].Version = version
+			changed = true
+		}
+	}
+	err = errSlice.ToError()
+
+	// the version of the Releaser follows the highest one of the repositories
+	if changed && !isPreRelease(option.scheme, releaser.Spec.Version) {
+		releaser.Spec.Version = getHighestVersion(option.scheme, releaser.Spec.Version, versions)
+	}
+	return
+}
+
+// getHighestVersion returns the highest valid version in the scheme, the invalid ones are ignored
+func getHighestVersion(scheme versioning.Scheme, current string, versions []string) (highest string) {
+	if scheme.Validate(current) == nil {
+		highest = current
+	}
+	for _, version := range versions {
+		if scheme.Validate(version) != nil {
+			continue
+		}
+		if highest == "" {
+			highest = version
+		} else if result, err := scheme.Compare(version, highest); err == nil && result > 0 {
+			highest = version
+		}
+	}
+	if highest == "" {
+		highest = current
+	}
+	return
+}
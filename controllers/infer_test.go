@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"context"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+	"time"
+)
+
+func Test_inferVersion(t *testing.T) {
+	calVer, err := versioning.NewCalVerWithClock("", func() time.Time {
+		return time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	})
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name            string
+		scheme          versioning.Scheme
+		previousVersion string
+		commits         []releaseCommit
+		want            string
+		wantErr         bool
+	}{{
+		name:            "invalid version",
+		previousVersion: "abc",
+		want:            "abc",
+		wantErr:         true,
+	}, {
+		name:            "no commits",
+		previousVersion: "v1.2.0",
+		want:            "v1.2.0",
+	}, {
+		name:            "bug fixes",
+		previousVersion: "v1.2.0",
+		commits:         []releaseCommit{{Type: "fix"}, {Type: "docs"}},
+		want:            "v1.2.1",
+	}, {
+		name:            "features",
+		previousVersion: "v1.2.3",
+		commits:         []releaseCommit{{Type: "fix"}, {Type: "feat"}},
+		want:            "v1.3.0",
+	}, {
+		name:            "breaking changes",
+		previousVersion: "1.2.3",
+		commits:         []releaseCommit{{Type: "feat"}, {Type: "fix", Breaking: true}},
+		want:            "2.0.0",
+	}, {
+		name:            "breaking changes before 1.0.0",
+		previousVersion: "v0.2.3",
+		commits:         []releaseCommit{{Type: "refactor", Breaking: true}},
+		want:            "v0.3.0",
+	}, {
+		name:            "previous version is a pre-release",
+		previousVersion: "v1.2.0-rc.1",
+		commits:         []releaseCommit{{Type: "fix"}},
+		want:            "v1.2.1",
+	}, {
+		name:            "calver",
+		scheme:          calVer,
+		previousVersion: "v2021.09.0",
+		commits:         []releaseCommit{{Type: "feat", Breaking: true}},
+		want:            "v2021.09.1",
+	}, {
+		name:            "invalid calver",
+		scheme:          calVer,
+		previousVersion: "v1.2.0",
+		commits:         []releaseCommit{{Type: "fix"}},
+		want:            "v1.2.0",
+		wantErr:         true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme := tt.scheme
+			if scheme == nil {
+				scheme = versioning.NewSemVer()
+			}
+			got, err := inferVersion(scheme, tt.previousVersion, tt.commits)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_getHighestVersion(t *testing.T) {
+	semVer := versioning.NewSemVer()
+	assert.Equal(t, "v1.3.0", getHighestVersion(semVer, "v1.2.1", []string{"v1.2.1", "v1.3.0", "invalid", "v1.2.9"}))
+	assert.Equal(t, "v1.2.1", getHighestVersion(semVer, "v1.2.1", []string{"v1.2.0"}))
+	assert.Equal(t, "v0.0.1", getHighestVersion(semVer, "invalid", []string{"v0.0.1"}))
+	assert.Equal(t, "invalid", getHighestVersion(semVer, "invalid", []string{"nightly"}))
+
+	// the calendar versions are compared by the scheme
+	calVer, err := versioning.NewCalVer("")
+	assert.Nil(t, err)
+	assert.Equal(t, "2021.10.0", getHighestVersion(calVer, "2021.09.12", []string{"2021.10.0", "2021.09.13"}))
+	assert.Equal(t, "2021.09.12", getHighestVersion(calVer, "2021.09.12", []string{"v1.2.3"}))
+}
+
+func TestReleaserReconciler_inferVersions(t *testing.T) {
+	dir, repo := newLocalRepository(t, "first commit")
+	head, err := repo.Head()
+	assert.Nil(t, err)
+	_, err = repo.CreateTag("v0.1.0", head.Hash(), nil)
+	assert.Nil(t, err)
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "fake", Name: "secret"}}
+	r := &ReleaserReconciler{
+		Client:      fake.NewFakeClientWithScheme(clientgoscheme.Scheme, secret),
+		GitCacheDir: t.TempDir(),
+	}
+	releaser := &devopsv1alpha1.Releaser{
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Phase:       devopsv1alpha1.PhaseDraft,
+			Version:     "v0.1.1",
+			Secret:      corev1.SecretReference{Namespace: "fake", Name: "secret"},
+			NextVersion: &devopsv1alpha1.NextVersion{InferFromCommits: true},
+			Repositories: []devopsv1alpha1.Repository{{
+				Address: dir,
+				Branch:  "master",
+				Version: "v0.1.1",
+			}, {
+				Address: dir,
+				Branch:  "master",
+				Version: "v0.2.0-alpha.0",
+			}},
+		},
+	}
+	assert.True(t, needToInferVersions(releaser))
+
+	// there is no new commit
+	changed, err := r.inferVersions(context.TODO(), releaser)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	wd, err := repo.Worktree()
+	assert.Nil(t, err)
+	_, err = wd.Commit("feat: a new feature", &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+	})
+	assert.Nil(t, err)
+
+	// the pre-release version is kept
+	changed, err = r.inferVersions(context.TODO(), releaser)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, "v0.2.0", releaser.Spec.Version)
+	assert.Equal(t, "v0.2.0", releaser.Spec.Repositories[0].Version)
+	assert.Equal(t, "v0.2.0-alpha.0", releaser.Spec.Repositories[1].Version)
+
+	releaser.Spec.Phase = devopsv1alpha1.PhaseReady
+	assert.False(t, needToInferVersions(releaser))
+}
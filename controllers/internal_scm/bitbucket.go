@@ -0,0 +1,336 @@
+package internal_scm
+
+import (
+	"fmt"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/bitbucket"
+	"github.com/jenkins-x/go-scm/scm/driver/stash"
+	"github.com/jenkins-x/go-scm/scm/transport"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	bitbucketCloudServer = "https://bitbucket.org"
+	bitbucketCloudAPI    = "https://api.bitbucket.org"
+	// bitbucketTokenUser is the username of the access tokens when cloning over HTTPS
+	bitbucketTokenUser = "x-token-auth"
+)
+
+// Bitbucket is the GitReleaser of Bitbucket Cloud.
+// Bitbucket has no releases, so a release is the tag which was pushed already.
+type Bitbucket struct {
+	repo     string
+	username string
+	token    string
+
+	apiURL string
+}
+
+// NewBitbucket creates a new instance of Bitbucket Cloud. The token is the app password of the user, or an access
+// token if the username is empty or x-token-auth.
+func NewBitbucket(repo, username, token string) *Bitbucket {
+	return &Bitbucket{
+		repo:     repo,
+		username: username,
+		token:    token,
+		apiURL:   bitbucketCloudAPI,
+	}
+}
+
+// isAccessToken checks if the token is an access token which works with the bearer authentication,
+// the app passwords only work with the basic authentication
+func (r *Bitbucket) isAccessToken() bool {
+	return r.username == "" || r.username == bitbucketTokenUser
+}
+
+// isBitbucketCloud checks if the server is Bitbucket Cloud instead of a Bitbucket Server
+func isBitbucketCloud(server string) bool {
+	server = strings.TrimSuffix(server, "/")
+	return server == "" || server == bitbucketCloudServer || server == bitbucketCloudAPI
+}
+
+func (r *Bitbucket) getClient() (client *scm.Client, err error) {
+	if client, err = bitbucket.New(r.apiURL); err != nil {
+		err = fmt.Errorf("failed to create bitbucket client, error: %v", err)
+		return
+	}
+	var auth http.RoundTripper = &transport.BasicAuth{
+		Username: r.username,
+		Password: r.token,
+	}
+	if r.isAccessToken() {
+		auth = &transport.BearerToken{
+			Token: r.token,
+		}
+	}
+	client.Client = &http.Client{
+		Transport: auth,
+	}
+	return
+}
+
+func (r *Bitbucket) getRESTClient() *restClient {
+	if r.isAccessToken() {
+		return newBearerRESTClient(r.apiURL, r.token)
+	}
+	return newBasicRESTClient(r.apiURL, r.username, r.token)
+}
+
+// Release returns the link of the tag, the description is ignored because Bitbucket has no releases
+func (r *Bitbucket) Release(version, commitish, description string, draft, prerelease bool) (link string, err error) {
+	tag := &struct {
+		Links struct {
+			HTML struct {
+				Href string `json:"href"`
+			} `json:"html"`
+		} `json:"links"`
+	}{}
+	path := fmt.Sprintf("2.0/repositories/%s/refs/tags/%s", r.repo, url.PathEscape(version))
+	if err = r.getRESTClient().do(http.MethodGet, path, nil, nil, tag); err != nil {
+		err = fmt.Errorf("failed to find tag %s of %s, error: %v", version, r.repo, err)
+		return
+	}
+
+	if link = tag.Links.HTML.Href; link == "" {
+		link = fmt.Sprintf("%s/%s/src/%s", bitbucketCloudServer, r.repo, version)
+	}
+	return
+}
+
+// bitbucketIssue is an issue of Bitbucket Cloud
+type bitbucketIssue struct {
+	ID      int    `json:"id,omitempty"`
+	Title   string `json:"title,omitempty"`
+	State   string `json:"state,omitempty"`
+	Content struct {
+		Raw string `json:"raw"`
+	} `json:"content"`
+}
+
+// isOpen checks if the state of an issue is open, the closed states of Bitbucket are: resolved, on hold, invalid, etc.
+func (i *bitbucketIssue) isOpen() bool {
+	return i.State == "new" || i.State == "open"
+}
+
+// findIssue returns the referred open issue, it is nil if there is no such issue
+func (r *Bitbucket) findIssue(client *restClient, ref IssueRef) (issue *bitbucketIssue, err error) {
+	issuesPath := fmt.Sprintf("2.0/repositories/%s/issues", r.repo)
+	if ref.Number != "" {
+		issue = &bitbucketIssue{}
+		if err = client.do(http.MethodGet, fmt.Sprintf("%s/%s", issuesPath, url.PathEscape(ref.Number)), nil, nil,
+			issue); err == nil && issue.isOpen() {
+			return
+		} else if err != nil && err != errNotFound {
+			err = fmt.Errorf("failed to find issue %s of %s, error: %v", ref.Number, r.repo, err)
+			return
+		}
+		issue, err = nil, nil
+	}
+
+	var unmarked *bitbucketIssue
+	for page := 1; ; page++ {
+		list := &struct {
+			Values []bitbucketIssue `json:"values"`
+			Next   string           `json:"next"`
+		}{}
+		query := url.Values{
+			"q":       []string{getBitbucketIssueQuery(ref)},
+			"pagelen": []string{"50"},
+			"page":    []string{strconv.Itoa(page)},
+		}
+		if err = client.do(http.MethodGet, issuesPath, query, nil, list); err == errNotFound {
+			err = fmt.Errorf("the issue tracker of %s is not enabled", r.repo)
+			return
+		} else if err != nil {
+			err = fmt.Errorf("failed to search the issues of %s, error: %v", r.repo, err)
+			return
+		}
+
+		for i := range list.Values {
+			if ref.matches(list.Values[i].Title, list.Values[i].Content.Raw) {
+				issue = &list.Values[i]
+				return
+			} else if unmarked == nil && ref.matchesUnmarked(list.Values[i].Title, list.Values[i].Content.Raw) {
+				unmarked = &list.Values[i]
+			}
+		}
+		if list.Next == "" {
+			break
+		}
+	}
+	issue = unmarked
+	return
+}
+
+// getBitbucketIssueQuery returns the query which filters the open issues by the title or the marker
+func getBitbucketIssueQuery(ref IssueRef) string {
+	filter := fmt.Sprintf(`title = "%s"`, escapeBitbucketQuery(ref.Title))
+	if ref.Marker != "" {
+		filter = fmt.Sprintf(`(%s OR content.raw ~ "%s")`, filter, escapeBitbucketQuery(ref.Marker))
+	}
+	return filter + ` AND (state = "new" OR state = "open")`
+}
+
+func escapeBitbucketQuery(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(value)
+}
+
+func (r *Bitbucket) commentIssue(client *restClient, id int, body string) error {
+	comment := bitbucketIssue{}
+	comment.Content.Raw = body
+	return client.do(http.MethodPost, fmt.Sprintf("2.0/repositories/%s/issues/%d/comments", r.repo, id), nil, comment, nil)
+}
+
+// CreateIssue comments on the referred open issue, or creates a new one.
+// It requires the issue tracker of the repository.
+func (r *Bitbucket) CreateIssue(ref IssueRef, body string) (number string, err error) {
+	client := r.getRESTClient()
+
+	var issue *bitbucketIssue
+	if issue, err = r.findIssue(client, ref); err != nil {
+		return
+	} else if issue == nil {
+		newIssue := bitbucketIssue{Title: ref.Title}
+		newIssue.Content.Raw = body
+		issue = &bitbucketIssue{}
+		err = client.do(http.MethodPost, fmt.Sprintf("2.0/repositories/%s/issues", r.repo), nil, newIssue, issue)
+	} else {
+		err = r.commentIssue(client, issue.ID, body)
+	}
+	if err == nil {
+		number = strconv.Itoa(issue.ID)
+	}
+	return
+}
+
+// CloseIssue comments on the referred open issue, then marks it as resolved
+func (r *Bitbucket) CloseIssue(ref IssueRef, comment string) (number string, err error) {
+	client := r.getRESTClient()
+
+	var issue *bitbucketIssue
+	if issue, err = r.findIssue(client, ref); err != nil || issue == nil {
+		return
+	}
+	if err = r.commentIssue(client, issue.ID, comment); err != nil {
+		err = fmt.Errorf("failed to comment on issue %d of %s, error: %v", issue.ID, r.repo, err)
+		return
+	}
+	if err = client.do(http.MethodPut, fmt.Sprintf("2.0/repositories/%s/issues/%d", r.repo, issue.ID), nil,
+		map[string]string{"state": "resolved"}, nil); err == nil {
+		number = strconv.Itoa(issue.ID)
+	}
+	return
+}
+
+// LabelIssue always fails because the issues of Bitbucket have no labels
+func (r *Bitbucket) LabelIssue(number string, labels []string) (err error) {
+	return fmt.Errorf("the issues of bitbucket do not have labels")
+}
+
+func (r *Bitbucket) CreatePullRequest(title, body, head, base string) (number int, link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, link, err = createPullRequest(client, r.repo, title, body, head, base)
+	}
+	return
+}
+
+func (r *Bitbucket) MergePullRequest(number int) (merged bool, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		merged, err = mergePullRequest(client, r.repo, number)
+	}
+	return
+}
+
+// BitbucketServer is the GitReleaser of Bitbucket Server (Data Center).
+// The repo is in the format of project/repository.
+type BitbucketServer struct {
+	server string
+	repo   string
+	token  string
+}
+
+// NewBitbucketServer creates a new instance of Bitbucket Server
+func NewBitbucketServer(server, repo, token string) *BitbucketServer {
+	return &BitbucketServer{
+		server: strings.TrimSuffix(server, "/"),
+		repo:   repo,
+		token:  token,
+	}
+}
+
+func (r *BitbucketServer) getClient() (client *scm.Client, err error) {
+	if client, err = stash.New(r.server); err != nil {
+		err = fmt.Errorf("failed to create bitbucket server client, error: %v", err)
+		return
+	}
+	client.Client = &http.Client{
+		Transport: &transport.BearerToken{
+			Token: r.token,
+		},
+	}
+	return
+}
+
+func (r *BitbucketServer) getProjectAndRepo() (project, repo string, err error) {
+	items := strings.Split(r.repo, "/")
+	if len(items) != 2 || items[0] == "" || items[1] == "" {
+		err = fmt.Errorf("invalid repository %s, it should be project/repository", r.repo)
+		return
+	}
+	project, repo = items[0], items[1]
+	return
+}
+
+// Release returns the link of the tag, the description is ignored because Bitbucket has no releases
+func (r *BitbucketServer) Release(version, commitish, description string, draft, prerelease bool) (link string, err error) {
+	var project, repo string
+	if project, repo, err = r.getProjectAndRepo(); err != nil {
+		return
+	}
+
+	path := fmt.Sprintf("rest/api/1.0/projects/%s/repos/%s/tags/%s", project, repo, url.PathEscape(version))
+	if err = newBearerRESTClient(r.server, r.token).do(http.MethodGet, path, nil, nil, nil); err != nil {
+		err = fmt.Errorf("failed to find tag %s of %s, error: %v", version, r.repo, err)
+		return
+	}
+	link = fmt.Sprintf("%s/projects/%s/repos/%s/browse?at=%s", r.server, project, repo,
+		url.QueryEscape("refs/tags/"+version))
+	return
+}
+
+// CreateIssue always fails because Bitbucket Server has no issue tracker
+func (r *BitbucketServer) CreateIssue(issue IssueRef, body string) (number string, err error) {
+	err = fmt.Errorf("bitbucket server does not have an issue tracker")
+	return
+}
+
+// CloseIssue does nothing because Bitbucket Server has no issue tracker, so there is no issue to close
+func (r *BitbucketServer) CloseIssue(issue IssueRef, comment string) (number string, err error) {
+	return
+}
+
+// LabelIssue always fails because Bitbucket Server has no issue tracker
+func (r *BitbucketServer) LabelIssue(number string, labels []string) (err error) {
+	return fmt.Errorf("bitbucket server does not have an issue tracker")
+}
+
+func (r *BitbucketServer) CreatePullRequest(title, body, head, base string) (number int, link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, link, err = createPullRequest(client, r.repo, title, body, head, base)
+	}
+	return
+}
+
+func (r *BitbucketServer) MergePullRequest(number int) (merged bool, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		merged, err = mergePullRequest(client, r.repo, number)
+	}
+	return
+}
@@ -0,0 +1,156 @@
+package internal_scm
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestIsBitbucketCloud(t *testing.T) {
+	assert.True(t, isBitbucketCloud(""))
+	assert.True(t, isBitbucketCloud("https://bitbucket.org/"))
+	assert.False(t, isBitbucketCloud("https://bitbucket.example.com"))
+}
+
+func TestBitbucket(t *testing.T) {
+	handlers := map[string]interface{}{
+		"GET /2.0/repositories/o/r/refs/tags/v1.0.0": map[string]interface{}{
+			"name": "v1.0.0", "links": map[string]interface{}{
+				"html": map[string]string{"href": "https://bitbucket.org/o/r/commits/tag/v1.0.0"},
+			},
+		},
+		"GET /2.0/repositories/o/r/issues":  map[string]interface{}{"values": []interface{}{}},
+		"POST /2.0/repositories/o/r/issues": map[string]interface{}{"id": 1},
+	}
+	server, requests := newFakeServer(t, handlers)
+	bitbucket := NewBitbucket("o/r", "", "token")
+	bitbucket.apiURL = server.URL
+
+	link, err := bitbucket.Release("v1.0.0", "master", "notes", false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://bitbucket.org/o/r/commits/tag/v1.0.0", link)
+	assert.Equal(t, "Bearer token", requests["GET /2.0/repositories/o/r/refs/tags/v1.0.0"].header.Get("Authorization"))
+
+	// the app passwords need the basic authentication
+	appPassword := NewBitbucket("o/r", "user", "password")
+	appPassword.apiURL = server.URL
+	_, err = appPassword.Release("v1.0.0", "master", "notes", false, false)
+	assert.Nil(t, err)
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	req.SetBasicAuth("user", "password")
+	assert.Equal(t, req.Header.Get("Authorization"),
+		requests["GET /2.0/repositories/o/r/refs/tags/v1.0.0"].header.Get("Authorization"))
+	assert.Nil(t, appPassword.getRESTClient().do(http.MethodGet, "/2.0/repositories/o/r/issues", nil, nil, nil))
+	assert.Equal(t, req.Header.Get("Authorization"),
+		requests["GET /2.0/repositories/o/r/issues"].header.Get("Authorization"))
+	delete(requests, "GET /2.0/repositories/o/r/issues")
+
+	// the tag must be pushed before releasing
+	_, err = bitbucket.Release("v1.0.1", "master", "notes", false, false)
+	assert.NotNil(t, err)
+
+	title := `release "v1" failed`
+	ref := IssueRef{Title: title, Marker: "<!-- v1 -->"}
+	number, err := bitbucket.CreateIssue(ref, "failed")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, `(title = "release \"v1\" failed" OR content.raw ~ "<!-- v1 -->") AND `+
+		`(state = "new" OR state = "open")`, requests["GET /2.0/repositories/o/r/issues"].query.Get("q"))
+	assert.Equal(t, title, requests["POST /2.0/repositories/o/r/issues"].body["title"])
+	assert.Equal(t, map[string]interface{}{"raw": "failed"}, requests["POST /2.0/repositories/o/r/issues"].body["content"])
+
+	// comment on the existing issue
+	handlers["GET /2.0/repositories/o/r/issues"] = map[string]interface{}{
+		"values": []interface{}{map[string]interface{}{"id": 1, "title": title, "state": "new",
+			"content": map[string]string{"raw": "failed <!-- v1 -->"}}},
+	}
+	handlers["POST /2.0/repositories/o/r/issues/1/comments"] = map[string]interface{}{"id": 1}
+	delete(requests, "POST /2.0/repositories/o/r/issues")
+	number, err = bitbucket.CreateIssue(ref, "failed again")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, map[string]interface{}{"raw": "failed again"},
+		requests["POST /2.0/repositories/o/r/issues/1/comments"].body["content"])
+	assert.NotContains(t, requests, "POST /2.0/repositories/o/r/issues")
+
+	handlers["PUT /2.0/repositories/o/r/issues/1"] = map[string]interface{}{"id": 1}
+	number, err = bitbucket.CloseIssue(ref, "resolved")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, map[string]interface{}{"raw": "resolved"},
+		requests["POST /2.0/repositories/o/r/issues/1/comments"].body["content"])
+	assert.Equal(t, "resolved", requests["PUT /2.0/repositories/o/r/issues/1"].body["state"])
+	assert.NotNil(t, bitbucket.LabelIssue(number, []string{"release-failure"}))
+
+	// find the issue by its number
+	handlers["GET /2.0/repositories/o/r/issues/1"] = map[string]interface{}{"id": 1, "title": "renamed", "state": "open"}
+	delete(requests, "GET /2.0/repositories/o/r/issues")
+	number, err = bitbucket.CloseIssue(IssueRef{Number: "1", Title: title, Marker: "<!-- v1 -->"}, "resolved")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.NotContains(t, requests, "GET /2.0/repositories/o/r/issues")
+
+	// the numbered issue was resolved, and there is no other open one
+	handlers["GET /2.0/repositories/o/r/issues/1"] = map[string]interface{}{"id": 1, "state": "resolved"}
+	handlers["GET /2.0/repositories/o/r/issues"] = map[string]interface{}{"values": []interface{}{}}
+	number, err = bitbucket.CloseIssue(IssueRef{Number: "1", Title: title, Marker: "<!-- v1 -->"}, "resolved")
+	assert.Nil(t, err)
+	assert.Empty(t, number)
+
+	// failed to find the numbered issue
+	handlers["GET /2.0/repositories/o/r/issues/1"] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	_, err = bitbucket.CloseIssue(IssueRef{Number: "1", Title: title, Marker: "<!-- v1 -->"}, "resolved")
+	assert.NotNil(t, err)
+
+	// the issue which was created before the markers is on the second page
+	handlers["GET /2.0/repositories/o/r/issues"] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		list := map[string]interface{}{"values": []interface{}{map[string]interface{}{"id": 3, "title": "other",
+			"state": "new", "content": map[string]string{"raw": "failed <!-- ks-releaser: ns/other -->"}}},
+			"next": "page 2"}
+		if req.URL.Query().Get("page") == "2" {
+			list = map[string]interface{}{"values": []interface{}{map[string]interface{}{"id": 2, "title": title,
+				"state": "open", "content": map[string]string{"raw": "failed"}}}}
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	})
+	handlers["POST /2.0/repositories/o/r/issues/2/comments"] = map[string]interface{}{"id": 1}
+	number, err = bitbucket.CreateIssue(ref, "failed again")
+	assert.Nil(t, err)
+	assert.Equal(t, "2", number)
+
+	// the issue tracker is disabled
+	bitbucket.repo = "o/disabled"
+	_, err = bitbucket.CreateIssue(ref, "failed")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "not enabled")
+}
+
+func TestBitbucketServer(t *testing.T) {
+	server, requests := newFakeServer(t, map[string]interface{}{
+		"GET /rest/api/1.0/projects/PROJ/repos/r/tags/v1.0.0": map[string]string{
+			"id": "refs/tags/v1.0.0", "displayId": "v1.0.0",
+		},
+	})
+
+	bitbucket := NewBitbucketServer(server.URL+"/", "PROJ/r", "token")
+	link, err := bitbucket.Release("v1.0.0", "master", "notes", false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/projects/PROJ/repos/r/browse?at=refs%2Ftags%2Fv1.0.0", link)
+	assert.Equal(t, "Bearer token",
+		requests["GET /rest/api/1.0/projects/PROJ/repos/r/tags/v1.0.0"].header.Get("Authorization"))
+
+	_, err = bitbucket.Release("v1.0.1", "master", "notes", false, false)
+	assert.NotNil(t, err)
+
+	_, err = NewBitbucketServer(server.URL, "r", "token").Release("v1.0.0", "master", "notes", false, false)
+	assert.NotNil(t, err)
+
+	_, err = bitbucket.CreateIssue(IssueRef{Title: "title"}, "body")
+	assert.NotNil(t, err)
+	number, err := bitbucket.CloseIssue(IssueRef{Title: "title"}, "resolved")
+	assert.Nil(t, err)
+	assert.Empty(t, number)
+}
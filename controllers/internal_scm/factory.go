@@ -2,34 +2,85 @@ package internal_scm
 
 import (
 	"context"
+	"fmt"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"net/http"
+	"strconv"
+	"strings"
 )
 
+// GitReleaser is the abstraction of the operations against a git provider
 type GitReleaser interface {
-	Release(version, commitish string, draft, prerelease bool) (err error)
-	CreateIssue(title, body string) (err error)
+	// Release creates a release (or publishes the existing draft one) with the description, returns the link of it
+	Release(version, commitish, description string, draft, prerelease bool) (link string, err error)
+	// CreateIssue comments on the referred open issue, or creates a new one, returns the number of the issue
+	CreateIssue(issue IssueRef, body string) (number string, err error)
+	// CloseIssue comments on the referred open issue then closes it, returns the number of the closed issue which is
+	// empty if there is no such issue
+	CloseIssue(issue IssueRef, comment string) (number string, err error)
+	// LabelIssue adds the labels to the issue
+	LabelIssue(number string, labels []string) (err error)
+	// CreatePullRequest opens a pull request from the head branch to the base one, or returns the existing open one
+	CreatePullRequest(title, body, head, base string) (number int, link string, err error)
+	// MergePullRequest merges the pull request once all the commit statuses succeeded, merged is false if they
+	// are pending
+	MergePullRequest(number int) (merged bool, err error)
 }
 
-func release(client *scm.Client, repo, version, commitish string, draft, prerelease bool) (err error) {
+// IssueMarkerPrefix is the beginning of the markers which ks-releaser puts into the body of its issues
+const IssueMarkerPrefix = "<!-- ks-releaser:"
+
+// issuePageSize is the number of the issues in each page when searching the open issues
+const issuePageSize = 100
+
+// IssueRef refers to an issue by its number. The open issue which has the marker in its body (or has the title if there
+// is no marker) is the fallback when the number is unknown, or the issue was closed.
+type IssueRef struct {
+	Number string
+	Title  string
+	Marker string
+}
+
+// matches checks if an issue is the referred one by the marker or the title
+func (r IssueRef) matches(title, body string) bool {
+	if r.Marker != "" {
+		return strings.Contains(body, r.Marker)
+	}
+	return title == r.Title
+}
+
+// matchesUnmarked checks if an issue has the title but no marker, it was created before the markers were introduced
+func (r IssueRef) matchesUnmarked(title, body string) bool {
+	return r.Marker != "" && title == r.Title && !strings.Contains(body, IssueMarkerPrefix)
+}
+
+func release(client *scm.Client, repo, version, commitish, description string, draft, prerelease bool) (link string, err error) {
 	releaseInput := &scm.ReleaseInput{
-		Title:      version,
-		Tag:        version,
-		Commitish:  commitish,
-		Draft:      draft,
-		Prerelease: prerelease,
+		Title:       version,
+		Tag:         version,
+		Description: description,
+		Commitish:   commitish,
+		Draft:       draft,
+		Prerelease:  prerelease,
 	}
 
 	// just publish the draft release if it is existing
 	release := findRelease(client, repo, version)
 	if release == nil {
-		_, _, err = client.Releases.Create(context.TODO(), repo, releaseInput)
+		release, _, err = client.Releases.Create(context.TODO(), repo, releaseInput)
 	} else if release.Draft {
-		releaseInput.Description = release.Description
+		// keep the description which was written by the maintainers
+		if release.Description != "" {
+			releaseInput.Description = release.Description
+		}
 		releaseInput.Title = release.Title
-		_, _, err = client.Releases.Update(context.TODO(), repo, release.ID, releaseInput)
+		release, _, err = client.Releases.Update(context.TODO(), repo, release.ID, releaseInput)
 	}
 	// ignore the existing release
+	if err == nil && release != nil {
+		link = release.Link
+	}
 	return
 }
 
@@ -56,15 +107,217 @@ func findRelease(client *scm.Client, repo, version string) (release *scm.Release
 	return
 }
 
-// GetGitProvider returns the GitReleaser implement by kind
-func GetGitProvider(kind, server, repo, token string) GitReleaser {
+// findIssue returns the referred open issue (not a pull request), it is nil if there is no such issue
+func findIssue(client *scm.Client, repo string, ref IssueRef) (issue *scm.Issue, err error) {
+	if ref.Number != "" {
+		var number int
+		if number, err = strconv.Atoi(ref.Number); err != nil {
+			err = fmt.Errorf("invalid issue number %s of %s", ref.Number, repo)
+			return
+		}
+		if issue, err = findIssueByNumber(client, repo, number); err != nil || (issue != nil && !issue.Closed) {
+			return
+		}
+		issue = nil
+	}
+
+	var unmarked *scm.Issue
+	for page := 1; ; page++ {
+		var list []*scm.Issue
+		if list, _, err = client.Issues.List(context.TODO(), repo, scm.IssueListOptions{
+			Page: page, Size: issuePageSize, Open: true,
+		}); err != nil {
+			err = fmt.Errorf("failed to list the issues of %s, error: %v", repo, err)
+			return
+		}
+		for i := range list {
+			if list[i].Closed || list[i].PullRequest {
+				continue
+			}
+			if ref.matches(list[i].Title, list[i].Body) {
+				issue = list[i]
+				return
+			} else if unmarked == nil && ref.matchesUnmarked(list[i].Title, list[i].Body) {
+				unmarked = list[i]
+			}
+		}
+		if len(list) < issuePageSize {
+			break
+		}
+	}
+	issue = unmarked
+	return
+}
+
+// findIssueByNumber returns nil if the issue does not exist
+func findIssueByNumber(client *scm.Client, repo string, number int) (issue *scm.Issue, err error) {
+	var resp *scm.Response
+	if issue, resp, err = client.Issues.Find(context.TODO(), repo, number); err != nil {
+		issue = nil
+		if err == scm.ErrNotFound || (resp != nil && resp.Status == http.StatusNotFound) {
+			err = nil
+		} else {
+			err = fmt.Errorf("failed to find issue %d of %s, error: %v", number, repo, err)
+		}
+	}
+	return
+}
+
+// createIssue comments on the referred open issue, or creates a new one
+func createIssue(client *scm.Client, repo string, ref IssueRef, body string) (number string, err error) {
+	ctx := context.TODO()
+	var issue *scm.Issue
+	if issue, err = findIssue(client, repo, ref); err != nil {
+		return
+	} else if issue != nil {
+		if _, _, err = client.Issues.CreateComment(ctx, repo, issue.Number, &scm.CommentInput{
+			Body: body,
+		}); err == nil {
+			number = strconv.Itoa(issue.Number)
+		}
+		return
+	}
+
+	if issue, _, err = client.Issues.Create(ctx, repo, &scm.IssueInput{
+		Title: ref.Title,
+		Body:  body,
+	}); err == nil {
+		number = strconv.Itoa(issue.Number)
+	}
+	return
+}
+
+func closeIssue(client *scm.Client, repo string, ref IssueRef, comment string) (number string, err error) {
+	ctx := context.TODO()
+	var issue *scm.Issue
+	if issue, err = findIssue(client, repo, ref); err != nil || issue == nil {
+		return
+	}
+
+	if _, _, err = client.Issues.CreateComment(ctx, repo, issue.Number, &scm.CommentInput{
+		Body: comment,
+	}); err != nil {
+		err = fmt.Errorf("failed to comment on issue %d of %s, error: %v", issue.Number, repo, err)
+		return
+	}
+	if _, err = client.Issues.Close(ctx, repo, issue.Number); err == nil {
+		number = strconv.Itoa(issue.Number)
+	}
+	return
+}
+
+func labelIssue(client *scm.Client, repo, number string, labels []string) (err error) {
+	var issueNumber int
+	if issueNumber, err = strconv.Atoi(number); err != nil {
+		err = fmt.Errorf("invalid issue number %s of %s", number, repo)
+		return
+	}
+
+	for _, label := range labels {
+		if _, err = client.Issues.AddLabel(context.TODO(), repo, issueNumber, label); err != nil {
+			err = fmt.Errorf("failed to add label %s to issue %s of %s, error: %v", label, number, repo, err)
+			return
+		}
+	}
+	return
+}
+
+func createPullRequest(client *scm.Client, repo, title, body, head, base string) (number int, link string, err error) {
+	ctx := context.TODO()
+	var list []*scm.PullRequest
+	if list, _, err = client.PullRequests.List(ctx, repo, scm.PullRequestListOptions{
+		Page: 1, Size: 100, Open: true,
+	}); err != nil {
+		err = fmt.Errorf("failed to list the pull requests of %s, error: %v", repo, err)
+		return
+	}
+	for _, pr := range list {
+		if pr.Head.Ref == head && pr.Base.Ref == base && !pr.Closed && !pr.Merged {
+			number, link = pr.Number, pr.Link
+			return
+		}
+	}
+
+	var pr *scm.PullRequest
+	if pr, _, err = client.PullRequests.Create(ctx, repo, &scm.PullRequestInput{
+		Title: title,
+		Head:  head,
+		Base:  base,
+		Body:  body,
+	}); err == nil {
+		number, link = pr.Number, pr.Link
+	}
+	return
+}
+
+func mergePullRequest(client *scm.Client, repo string, number int) (merged bool, err error) {
+	ctx := context.TODO()
+	var pr *scm.PullRequest
+	if pr, _, err = client.PullRequests.Find(ctx, repo, number); err != nil {
+		err = fmt.Errorf("failed to find pull request %d of %s, error: %v", number, repo, err)
+		return
+	} else if pr.Merged {
+		merged = true
+		return
+	} else if pr.Closed {
+		err = fmt.Errorf("pull request %d of %s was closed without merging", number, repo)
+		return
+	}
+
+	sha := pr.Sha
+	if sha == "" {
+		sha = pr.Head.Sha
+	}
+	var status *scm.CombinedStatus
+	if status, _, err = client.Repositories.FindCombinedStatus(ctx, repo, sha); err != nil {
+		err = fmt.Errorf("failed to find the statuses of pull request %d of %s, error: %v", number, repo, err)
+		return
+	}
+
+	switch state := getCombinedState(status.Statuses); state {
+	case scm.StateSuccess:
+		if _, err = client.PullRequests.Merge(ctx, repo, number, &scm.PullRequestMergeOptions{}); err == nil {
+			merged = true
+		}
+	case scm.StatePending:
+	default:
+		err = fmt.Errorf("the checks of pull request %d of %s are %s", number, repo, state)
+	}
+	return
+}
+
+// getCombinedState returns failure if any status failed, or pending if any status is not finished,
+// or success if all statuses (or none) succeeded
+func getCombinedState(statuses []*scm.Status) (state scm.State) {
+	state = scm.StateSuccess
+	for _, status := range statuses {
+		switch status.State {
+		case scm.StateSuccess:
+		case scm.StateFailure, scm.StateError, scm.StateCanceled:
+			return scm.StateFailure
+		default:
+			state = scm.StatePending
+		}
+	}
+	return
+}
+
+// GetGitProvider returns the GitReleaser implement by kind, the username is only used by Bitbucket Cloud
+func GetGitProvider(kind, server, repo, username, token string) GitReleaser {
 	switch v1alpha1.Provider(kind) {
 	case v1alpha1.ProviderGitHub:
-		return NewGitHub(repo, token)
+		return NewGitHub(server, repo, token)
 	case v1alpha1.ProviderGitlab:
-		return NewGitlab(repo, token)
+		return NewGitlab(server, repo, token)
 	case v1alpha1.ProviderGitea:
 		return NewGitea(server, repo, token)
+	case v1alpha1.ProviderGitee:
+		return NewGitee(server, repo, token)
+	case v1alpha1.ProviderBitbucket:
+		if isBitbucketCloud(server) {
+			return NewBitbucket(repo, username, token)
+		}
+		return NewBitbucketServer(server, repo, token)
 	}
 	return nil
 }
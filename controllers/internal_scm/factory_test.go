@@ -1,10 +1,50 @@
 package internal_scm
 
 import (
+	"encoding/json"
+	"fmt"
+	"github.com/jenkins-x/go-scm/scm"
+	"github.com/jenkins-x/go-scm/scm/driver/fake"
+	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 )
 
+// fakeRequest is a request which was received by the fake server
+type fakeRequest struct {
+	header http.Header
+	query  url.Values
+	body   map[string]interface{}
+}
+
+// newFakeServer starts a fake git provider which responds by the method and path, the requests are recorded by them.
+// A handler is either the response which is encoded as JSON, or an http.HandlerFunc.
+func newFakeServer(t *testing.T, handlers map[string]interface{}) (server *httptest.Server, requests map[string]fakeRequest) {
+	requests = map[string]fakeRequest{}
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := fmt.Sprintf("%s %s", req.Method, req.URL.Path)
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		requests[key] = fakeRequest{header: req.Header, query: req.URL.Query(), body: body}
+
+		response, ok := handlers[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		} else if handle, ok := response.(http.HandlerFunc); ok {
+			handle(w, req)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return
+}
+
 func TestGetGitProvider(t *testing.T) {
 	type args struct {
 		kind  string
@@ -33,6 +73,18 @@ func TestGetGitProvider(t *testing.T) {
 			kind: "gitea",
 		},
 		exist: true,
+	}, {
+		name: "gitee",
+		args: args{
+			kind: "gitee",
+		},
+		exist: true,
+	}, {
+		name: "bitbucket",
+		args: args{
+			kind: "bitbucket",
+		},
+		exist: true,
 	}, {
 		name:  "fake",
 		args:  args{},
@@ -40,7 +92,7 @@ func TestGetGitProvider(t *testing.T) {
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := GetGitProvider(tt.args.kind, "", tt.args.repo, tt.args.token)
+			got := GetGitProvider(tt.args.kind, "", tt.args.repo, "", tt.args.token)
 			if tt.exist {
 				assert.NotNil(t, got)
 			} else {
@@ -49,3 +101,115 @@ func TestGetGitProvider(t *testing.T) {
 		})
 	}
 }
+
+func TestIssueRef_matches(t *testing.T) {
+	ref := IssueRef{Title: "releaser", Marker: IssueMarkerPrefix + " ns/releaser -->"}
+	tests := []struct {
+		name     string
+		ref      IssueRef
+		title    string
+		body     string
+		matched  bool
+		unmarked bool
+	}{{
+		name:    "marker",
+		ref:     ref,
+		title:   "renamed",
+		body:    "failed\n" + ref.Marker,
+		matched: true,
+	}, {
+		name:     "created before the markers",
+		ref:      ref,
+		title:    "releaser",
+		body:     "failed",
+		unmarked: true,
+	}, {
+		name:  "marker of another releaser",
+		ref:   ref,
+		title: "releaser",
+		body:  "failed\n" + IssueMarkerPrefix + " other/releaser -->",
+	}, {
+		name:    "title without marker",
+		ref:     IssueRef{Title: "releaser"},
+		title:   "releaser",
+		matched: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.matched, tt.ref.matches(tt.title, tt.body))
+			assert.Equal(t, tt.unmarked, tt.ref.matchesUnmarked(tt.title, tt.body))
+		})
+	}
+}
+
+func TestSelfHostedClient(t *testing.T) {
+	client, err := NewGitHub("", "a/b", "token").getClient()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.github.com/", client.BaseURL.String())
+
+	client, err = NewGitHub("https://github.example.com/", "a/b", "token").getClient()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://github.example.com/api/v3/", client.BaseURL.String())
+
+	client, err = NewGitlab("", "a/b", "token").getClient()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://gitlab.com/", client.BaseURL.String())
+
+	client, err = NewGitlab("https://gitlab.example.com", "a/b", "token").getClient()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://gitlab.example.com/", client.BaseURL.String())
+}
+
+func TestSupportsRelease(t *testing.T) {
+	// the webhook relies on the providers which have the implementations
+	for _, provider := range []v1alpha1.Provider{v1alpha1.ProviderGitHub, v1alpha1.ProviderGitlab,
+		v1alpha1.ProviderBitbucket, v1alpha1.ProviderGitee, v1alpha1.ProviderGitea, v1alpha1.ProviderUnknown} {
+		assert.Equal(t, provider.SupportsRelease(), GetGitProvider(string(provider), "", "", "", "") != nil, provider)
+		assert.Equal(t, provider.SupportsPullRequest(), GetGitProvider(string(provider), "", "", "", "") != nil,
+			provider)
+	}
+}
+
+func TestPullRequest(t *testing.T) {
+	client, data := fake.NewDefault()
+
+	number, _, err := createPullRequest(client, "linuxsuren/gitops", "title", "body", "ks-releaser/test", "master")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, number)
+	assert.Equal(t, "ks-releaser/test", data.PullRequests[number].Head.Ref)
+
+	// reuse the existing pull request
+	number, _, err = createPullRequest(client, "linuxsuren/gitops", "title", "body", "ks-releaser/test", "master")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, number)
+	assert.Equal(t, 1, len(data.PullRequests))
+
+	// wait for the pending checks
+	data.PullRequests[number].Sha = "sha"
+	data.Statuses["sha"] = []*scm.Status{{State: scm.StateSuccess}, {State: scm.StatePending}}
+	merged, err := mergePullRequest(client, "linuxsuren/gitops", number)
+	assert.Nil(t, err)
+	assert.False(t, merged)
+
+	data.Statuses["sha"][1].State = scm.StateSuccess
+	merged, err = mergePullRequest(client, "linuxsuren/gitops", number)
+	assert.Nil(t, err)
+	assert.True(t, merged)
+	assert.True(t, data.PullRequests[number].Merged)
+
+	// the checks failed
+	number, _, err = createPullRequest(client, "linuxsuren/gitops", "title", "body", "ks-releaser/failed", "master")
+	assert.Nil(t, err)
+	data.PullRequests[number].Sha = "failed"
+	data.Statuses["failed"] = []*scm.Status{{State: scm.StateFailure}}
+	merged, err = mergePullRequest(client, "linuxsuren/gitops", number)
+	assert.NotNil(t, err)
+	assert.False(t, merged)
+}
+
+func TestGetCombinedState(t *testing.T) {
+	assert.Equal(t, scm.StateSuccess, getCombinedState(nil))
+	assert.Equal(t, scm.StateSuccess, getCombinedState([]*scm.Status{{State: scm.StateSuccess}}))
+	assert.Equal(t, scm.StatePending, getCombinedState([]*scm.Status{{State: scm.StateSuccess}, {State: scm.StateRunning}}))
+	assert.Equal(t, scm.StateFailure, getCombinedState([]*scm.Status{{State: scm.StatePending}, {State: scm.StateError}}))
+}
@@ -1,9 +1,12 @@
 package internal_scm
 
 import (
+	"context"
 	"fmt"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/driver/gitea"
+	"net/http"
+	"strings"
 )
 
 type Gitea struct {
@@ -22,16 +25,106 @@ func NewGitea(server, repo, token string) *Gitea {
 	}
 }
 
-func (r *Gitea) Release(version, commitish string, draft, prerelease bool) (err error) {
-	var client *scm.Client
+func (r *Gitea) getClient() (client *scm.Client, err error) {
 	if client, err = gitea.NewWithToken(r.server, r.token); err != nil || client == nil {
 		err = fmt.Errorf("failed to create gitea client, error: %v", err)
-	} else {
-		err = release(client, r.repo, version, commitish, draft, prerelease)
+		return
 	}
+	client.Issues = &giteaIssueService{IssueService: client.Issues, client: r.getRESTClient()}
 	return
 }
 
-func (r *Gitea) CreateIssue(title, body string) (err error) {
-	return fmt.Errorf("not support yet")
+func (r *Gitea) getRESTClient() *restClient {
+	return &restClient{
+		baseURL: strings.TrimSuffix(r.server, "/") + "/api/v1",
+		authorize: func(req *http.Request) {
+			if r.token != "" {
+				req.Header.Set("Authorization", "token "+r.token)
+			}
+		},
+	}
+}
+
+// giteaIssueService finds the issues by the REST API, because the driver of Gitea panics when the issue does not exist
+type giteaIssueService struct {
+	scm.IssueService
+	client *restClient
+}
+
+type giteaIssue struct {
+	Number      int       `json:"number"`
+	Title       string    `json:"title"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	State       string    `json:"state"`
+	PullRequest *struct{} `json:"pull_request"`
+}
+
+func (s *giteaIssueService) Find(ctx context.Context, repo string, number int) (issue *scm.Issue, resp *scm.Response,
+	err error) {
+	out := &giteaIssue{}
+	path := fmt.Sprintf("repos/%s/issues/%d", repo, number)
+	if err = s.client.do(http.MethodGet, path, nil, nil, out); err == errNotFound {
+		resp, err = &scm.Response{Status: http.StatusNotFound}, scm.ErrNotFound
+		return
+	} else if err != nil {
+		return
+	}
+	issue = &scm.Issue{
+		Number:      out.Number,
+		Title:       out.Title,
+		Body:        out.Body,
+		Link:        out.HTMLURL,
+		Closed:      out.State == "closed",
+		PullRequest: out.PullRequest != nil,
+	}
+	return
+}
+
+func (r *Gitea) Release(version, commitish, description string, draft, prerelease bool) (link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		link, err = release(client, r.repo, version, commitish, description, draft, prerelease)
+	}
+	return
+}
+
+func (r *Gitea) CreateIssue(issue IssueRef, body string) (number string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, err = createIssue(client, r.repo, issue, body)
+	}
+	return
+}
+
+func (r *Gitea) CloseIssue(issue IssueRef, comment string) (number string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, err = closeIssue(client, r.repo, issue, comment)
+	}
+	return
+}
+
+func (r *Gitea) LabelIssue(number string, labels []string) (err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		err = labelIssue(client, r.repo, number, labels)
+	}
+	return
+}
+
+func (r *Gitea) CreatePullRequest(title, body, head, base string) (number int, link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, link, err = createPullRequest(client, r.repo, title, body, head, base)
+	}
+	return
+}
+
+func (r *Gitea) MergePullRequest(number int) (merged bool, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		merged, err = mergePullRequest(client, r.repo, number)
+	}
+	return
 }
@@ -0,0 +1,97 @@
+package internal_scm
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestGitea_CreateIssue(t *testing.T) {
+	user := map[string]interface{}{"login": "bot"}
+	handlers := map[string]interface{}{
+		"GET /api/v1/version":           map[string]string{"version": "1.14.0"},
+		"GET /api/v1/repos/o/r/issues":  []map[string]interface{}{},
+		"POST /api/v1/repos/o/r/issues": map[string]interface{}{"number": 1, "title": "releaser", "user": user},
+	}
+	server, requests := newFakeServer(t, handlers)
+	gitea := NewGitea(server.URL, "o/r", "token")
+
+	number, err := gitea.CreateIssue(IssueRef{Title: "releaser"}, "failed")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, "open", requests["GET /api/v1/repos/o/r/issues"].query.Get("state"))
+	assert.Equal(t, "releaser", requests["POST /api/v1/repos/o/r/issues"].body["title"])
+	assert.Equal(t, "failed", requests["POST /api/v1/repos/o/r/issues"].body["body"])
+
+	// comment on the existing issue
+	handlers["GET /api/v1/repos/o/r/issues"] = []map[string]interface{}{
+		{"number": 1, "title": "releaser", "state": "open", "user": user},
+	}
+	handlers["POST /api/v1/repos/o/r/issues/1/comments"] = map[string]interface{}{"id": 1, "user": user}
+	delete(requests, "POST /api/v1/repos/o/r/issues")
+	number, err = gitea.CreateIssue(IssueRef{Title: "releaser"}, "failed again")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, "failed again", requests["POST /api/v1/repos/o/r/issues/1/comments"].body["body"])
+	assert.NotContains(t, requests, "POST /api/v1/repos/o/r/issues")
+}
+
+func TestGitea_FindIssue(t *testing.T) {
+	user := map[string]interface{}{"login": "bot"}
+	handlers := map[string]interface{}{
+		"GET /api/v1/version": map[string]string{"version": "1.14.0"},
+		"GET /api/v1/repos/o/r/issues": []map[string]interface{}{
+			{"number": 2, "title": "releaser", "state": "open", "user": user},
+			{"number": 1, "title": "renamed", "body": "failed <!-- releaser -->", "state": "open", "user": user},
+		},
+		"GET /api/v1/repos/o/r/issues/3": map[string]interface{}{"number": 3, "title": "other", "state": "open",
+			"user": user},
+	}
+	server, requests := newFakeServer(t, handlers)
+	client, err := NewGitea(server.URL, "o/r", "token").getClient()
+	assert.Nil(t, err)
+
+	// find the issue by the marker instead of the title
+	issue, err := findIssue(client, "o/r", IssueRef{Title: "releaser", Marker: "<!-- releaser -->"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, issue.Number)
+
+	// find the issue by its number without searching
+	delete(requests, "GET /api/v1/repos/o/r/issues")
+	issue, err = findIssue(client, "o/r", IssueRef{Number: "3", Title: "releaser", Marker: "<!-- releaser -->"})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, issue.Number)
+	assert.NotContains(t, requests, "GET /api/v1/repos/o/r/issues")
+
+	// the numbered issue does not exist
+	issue, err = findIssue(client, "o/r", IssueRef{Number: "4", Title: "releaser", Marker: "<!-- releaser -->"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, issue.Number)
+	assert.Equal(t, "token token", requests["GET /api/v1/repos/o/r/issues/4"].header.Get("Authorization"))
+
+	// failed to find the numbered issue
+	handlers["GET /api/v1/repos/o/r/issues/5"] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	_, err = findIssue(client, "o/r", IssueRef{Number: "5", Title: "releaser", Marker: "<!-- releaser -->"})
+	assert.NotNil(t, err)
+
+	// the issue which was created before the markers is on the second page
+	handlers["GET /api/v1/repos/o/r/issues"] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		list := make([]map[string]interface{}, 0, issuePageSize)
+		if req.URL.Query().Get("page") == "1" {
+			for i := 0; i < issuePageSize; i++ {
+				list = append(list, map[string]interface{}{"number": i + 10, "title": "other", "state": "open",
+					"user": user})
+			}
+		} else {
+			list = append(list, map[string]interface{}{"number": 2, "title": "releaser", "state": "open",
+				"user": user})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	})
+	issue, err = findIssue(client, "o/r", IssueRef{Title: "releaser", Marker: "<!-- ks-releaser: ns/releaser -->"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, issue.Number)
+}
@@ -0,0 +1,285 @@
+package internal_scm
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const giteeServer = "https://gitee.com"
+
+// Gitee is the GitReleaser of Gitee which talks to its REST API v5 directly, because go-scm has no driver of it
+type Gitee struct {
+	server string
+	repo   string
+	token  string
+}
+
+// NewGitee creates a new instance, the server is https://gitee.com if it is empty
+func NewGitee(server, repo, token string) *Gitee {
+	if server == "" {
+		server = giteeServer
+	}
+	return &Gitee{
+		server: strings.TrimSuffix(server, "/"),
+		repo:   repo,
+		token:  token,
+	}
+}
+
+func (r *Gitee) getRESTClient() *restClient {
+	return &restClient{
+		baseURL: r.server + "/api/v5",
+		authorize: func(req *http.Request) {
+			if r.token != "" {
+				query := req.URL.Query()
+				query.Set("access_token", r.token)
+				req.URL.RawQuery = query.Encode()
+			}
+		},
+	}
+}
+
+type giteeRelease struct {
+	ID              int    `json:"id,omitempty"`
+	TagName         string `json:"tag_name"`
+	Name            string `json:"name"`
+	Body            string `json:"body"`
+	Prerelease      bool   `json:"prerelease"`
+	TargetCommitish string `json:"target_commitish"`
+}
+
+// Release creates a release if it does not exist. Gitee has no draft releases, so the draft flag is ignored.
+func (r *Gitee) Release(version, commitish, description string, draft, prerelease bool) (link string, err error) {
+	client := r.getRESTClient()
+
+	existing := &giteeRelease{}
+	if err = client.do(http.MethodGet, fmt.Sprintf("repos/%s/releases/tags/%s", r.repo, url.PathEscape(version)),
+		nil, nil, existing); err != nil && err != errNotFound {
+		err = fmt.Errorf("failed to find release %s of %s, error: %v", version, r.repo, err)
+		return
+	}
+
+	// ignore the existing release
+	if existing.ID == 0 {
+		if description == "" {
+			// the description is required by Gitee
+			description = version
+		}
+		if err = client.do(http.MethodPost, fmt.Sprintf("repos/%s/releases", r.repo), nil, &giteeRelease{
+			TagName:         version,
+			Name:            version,
+			Body:            description,
+			Prerelease:      prerelease,
+			TargetCommitish: commitish,
+		}, nil); err != nil {
+			err = fmt.Errorf("failed to create release %s of %s, error: %v", version, r.repo, err)
+			return
+		}
+	}
+	link = fmt.Sprintf("%s/%s/releases/tag/%s", r.server, r.repo, version)
+	return
+}
+
+type giteeIssue struct {
+	Number string `json:"number,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Body   string `json:"body,omitempty"`
+	Repo   string `json:"repo,omitempty"`
+	State  string `json:"state,omitempty"`
+}
+
+// isOpen checks if the state of an issue is open, the states of Gitee are: open, progressing, closed, rejected
+func (i *giteeIssue) isOpen() bool {
+	return i.State == "open" || i.State == "progressing"
+}
+
+// findIssue returns the referred open issue, it is nil if there is no such issue
+func (r *Gitee) findIssue(client *restClient, ref IssueRef) (issue *giteeIssue, err error) {
+	if ref.Number != "" {
+		issue = &giteeIssue{}
+		if err = client.do(http.MethodGet, fmt.Sprintf("repos/%s/issues/%s", r.repo, url.PathEscape(ref.Number)),
+			nil, nil, issue); err == nil && issue.isOpen() {
+			return
+		} else if err != nil && err != errNotFound {
+			err = fmt.Errorf("failed to find issue %s of %s, error: %v", ref.Number, r.repo, err)
+			return
+		}
+		issue, err = nil, nil
+	}
+
+	var unmarked *giteeIssue
+	for page := 1; ; page++ {
+		var list []giteeIssue
+		if err = client.do(http.MethodGet, fmt.Sprintf("repos/%s/issues", r.repo), url.Values{
+			"state":    []string{"open"},
+			"page":     []string{strconv.Itoa(page)},
+			"per_page": []string{strconv.Itoa(issuePageSize)},
+		}, nil, &list); err != nil {
+			err = fmt.Errorf("failed to list the issues of %s, error: %v", r.repo, err)
+			return
+		}
+		for i := range list {
+			if ref.matches(list[i].Title, list[i].Body) {
+				issue = &list[i]
+				return
+			} else if unmarked == nil && ref.matchesUnmarked(list[i].Title, list[i].Body) {
+				unmarked = &list[i]
+			}
+		}
+		if len(list) < issuePageSize {
+			break
+		}
+	}
+	issue = unmarked
+	return
+}
+
+// getOwnerAndRepo splits the repo, the issues belong to the owner instead of the repository in some APIs of Gitee
+func (r *Gitee) getOwnerAndRepo() (owner, repo string) {
+	owner = r.repo
+	if i := strings.Index(r.repo, "/"); i > 0 {
+		owner, repo = r.repo[:i], r.repo[i+1:]
+	}
+	return
+}
+
+// CreateIssue comments on the referred open issue, or creates a new one
+func (r *Gitee) CreateIssue(ref IssueRef, body string) (number string, err error) {
+	client := r.getRESTClient()
+
+	var issue *giteeIssue
+	if issue, err = r.findIssue(client, ref); err != nil {
+		return
+	} else if issue != nil {
+		if err = client.do(http.MethodPost, fmt.Sprintf("repos/%s/issues/%s/comments", r.repo, issue.Number), nil,
+			&giteeIssue{Body: body}, nil); err == nil {
+			number = issue.Number
+		}
+		return
+	}
+
+	owner, repo := r.getOwnerAndRepo()
+	issue = &giteeIssue{}
+	if err = client.do(http.MethodPost, fmt.Sprintf("repos/%s/issues", owner), nil, &giteeIssue{
+		Title: ref.Title,
+		Body:  body,
+		Repo:  repo,
+	}, issue); err == nil {
+		number = issue.Number
+	}
+	return
+}
+
+func (r *Gitee) CloseIssue(ref IssueRef, comment string) (number string, err error) {
+	client := r.getRESTClient()
+
+	var issue *giteeIssue
+	if issue, err = r.findIssue(client, ref); err != nil || issue == nil {
+		return
+	}
+	if err = client.do(http.MethodPost, fmt.Sprintf("repos/%s/issues/%s/comments", r.repo, issue.Number), nil,
+		&giteeIssue{Body: comment}, nil); err != nil {
+		err = fmt.Errorf("failed to comment on issue %s of %s, error: %v", issue.Number, r.repo, err)
+		return
+	}
+
+	owner, repo := r.getOwnerAndRepo()
+	if err = client.do(http.MethodPatch, fmt.Sprintf("repos/%s/issues/%s", owner, issue.Number), nil, &giteeIssue{
+		Repo:  repo,
+		State: "closed",
+	}, nil); err == nil {
+		number = issue.Number
+	}
+	return
+}
+
+func (r *Gitee) LabelIssue(number string, labels []string) (err error) {
+	return r.getRESTClient().do(http.MethodPost, fmt.Sprintf("repos/%s/issues/%s/labels", r.repo, url.PathEscape(number)),
+		nil, labels, nil)
+}
+
+type giteePullRequest struct {
+	Number    int    `json:"number,omitempty"`
+	Title     string `json:"title,omitempty"`
+	Body      string `json:"body,omitempty"`
+	HTMLURL   string `json:"html_url,omitempty"`
+	State     string `json:"state,omitempty"`
+	MergedAt  string `json:"merged_at,omitempty"`
+	Mergeable bool   `json:"mergeable,omitempty"`
+	Head      struct {
+		Ref string `json:"ref"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+}
+
+type giteePullRequestInput struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Head  string `json:"head"`
+	Base  string `json:"base"`
+}
+
+// CreatePullRequest opens a pull request from the head branch to the base one, or returns the existing open one
+func (r *Gitee) CreatePullRequest(title, body, head, base string) (number int, link string, err error) {
+	client := r.getRESTClient()
+	pullsPath := fmt.Sprintf("repos/%s/pulls", r.repo)
+
+	var list []giteePullRequest
+	if err = client.do(http.MethodGet, pullsPath, url.Values{
+		"state":    []string{"open"},
+		"base":     []string{base},
+		"per_page": []string{"100"},
+	}, nil, &list); err != nil {
+		err = fmt.Errorf("failed to list the pull requests of %s, error: %v", r.repo, err)
+		return
+	}
+	for _, pr := range list {
+		if pr.Head.Ref == head && pr.Base.Ref == base && pr.State == "open" {
+			number, link = pr.Number, pr.HTMLURL
+			return
+		}
+	}
+
+	pr := &giteePullRequest{}
+	if err = client.do(http.MethodPost, pullsPath, nil, &giteePullRequestInput{
+		Title: title,
+		Body:  body,
+		Head:  head,
+		Base:  base,
+	}, pr); err == nil {
+		number, link = pr.Number, pr.HTMLURL
+	}
+	return
+}
+
+// MergePullRequest merges the pull request once it is mergeable, because Gitee has no commit statuses
+func (r *Gitee) MergePullRequest(number int) (merged bool, err error) {
+	client := r.getRESTClient()
+	prPath := fmt.Sprintf("repos/%s/pulls/%d", r.repo, number)
+
+	pr := &giteePullRequest{}
+	if err = client.do(http.MethodGet, prPath, nil, nil, pr); err != nil {
+		err = fmt.Errorf("failed to find pull request %d of %s, error: %v", number, r.repo, err)
+		return
+	} else if pr.State == "merged" || pr.MergedAt != "" {
+		merged = true
+		return
+	} else if pr.State == "closed" {
+		err = fmt.Errorf("pull request %d of %s was closed without merging", number, r.repo)
+		return
+	} else if !pr.Mergeable {
+		return
+	}
+
+	if err = client.do(http.MethodPut, prPath+"/merge", nil, map[string]string{
+		"merge_method": "merge",
+	}, nil); err == nil {
+		merged = true
+	}
+	return
+}
@@ -0,0 +1,196 @@
+package internal_scm
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestGitee_Release(t *testing.T) {
+	server, requests := newFakeServer(t, map[string]interface{}{
+		"GET /api/v5/repos/o/r/releases/tags/v1.0.0": map[string]interface{}{"id": 1, "tag_name": "v1.0.0"},
+		"POST /api/v5/repos/o/r/releases":            map[string]interface{}{"id": 2, "tag_name": "v1.0.1"},
+	})
+	gitee := NewGitee(server.URL, "o/r", "token")
+
+	// keep the existing release
+	link, err := gitee.Release("v1.0.0", "master", "notes", false, false)
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/o/r/releases/tag/v1.0.0", link)
+	assert.Equal(t, "token", requests["GET /api/v5/repos/o/r/releases/tags/v1.0.0"].query.Get("access_token"))
+	assert.NotContains(t, requests, "POST /api/v5/repos/o/r/releases")
+
+	link, err = gitee.Release("v1.0.1", "master", "notes", false, true)
+	assert.Nil(t, err)
+	assert.Equal(t, server.URL+"/o/r/releases/tag/v1.0.1", link)
+	assert.Equal(t, map[string]interface{}{
+		"tag_name":         "v1.0.1",
+		"name":             "v1.0.1",
+		"body":             "notes",
+		"prerelease":       true,
+		"target_commitish": "master",
+	}, requests["POST /api/v5/repos/o/r/releases"].body)
+}
+
+func TestGitee_TokenNotInError(t *testing.T) {
+	server, _ := newFakeServer(t, nil)
+	server.Close()
+	gitee := NewGitee(server.URL, "o/r", "secret-token")
+
+	_, err := gitee.Release("v1.0.0", "master", "notes", false, false)
+	if assert.NotNil(t, err) {
+		assert.NotContains(t, err.Error(), "secret-token")
+		assert.Contains(t, err.Error(), "GET repos/o/r/releases/tags/v1.0.0")
+	}
+}
+
+func TestGitee_CreateIssue(t *testing.T) {
+	server, requests := newFakeServer(t, map[string]interface{}{
+		"GET /api/v5/repos/o/r/issues":              []map[string]interface{}{{"number": "I1", "title": "existing"}},
+		"POST /api/v5/repos/o/r/issues/I1/comments": map[string]interface{}{"id": 1},
+		"POST /api/v5/repos/o/issues":               map[string]interface{}{"number": "I2"},
+	})
+	gitee := NewGitee(server.URL, "o/r", "token")
+
+	number, err := gitee.CreateIssue(IssueRef{Title: "existing"}, "failed again")
+	assert.Nil(t, err)
+	assert.Equal(t, "I1", number)
+	assert.Equal(t, "failed again", requests["POST /api/v5/repos/o/r/issues/I1/comments"].body["body"])
+
+	number, err = gitee.CreateIssue(IssueRef{Title: "new"}, "failed")
+	assert.Nil(t, err)
+	assert.Equal(t, "I2", number)
+	assert.Equal(t, map[string]interface{}{
+		"title": "new",
+		"body":  "failed",
+		"repo":  "r",
+	}, requests["POST /api/v5/repos/o/issues"].body)
+}
+
+func TestGitee_PullRequest(t *testing.T) {
+	handlers := map[string]interface{}{
+		"GET /api/v5/repos/o/r/pulls":   []map[string]interface{}{},
+		"POST /api/v5/repos/o/r/pulls":  map[string]interface{}{"number": 1, "html_url": "https://gitee.com/o/r/pulls/1"},
+		"GET /api/v5/repos/o/r/pulls/1": map[string]interface{}{"number": 1, "state": "open", "mergeable": false},
+	}
+	server, requests := newFakeServer(t, handlers)
+	gitee := NewGitee(server.URL, "o/r", "token")
+
+	number, link, err := gitee.CreatePullRequest("title", "body", "ks-releaser/test", "master")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, number)
+	assert.Equal(t, "https://gitee.com/o/r/pulls/1", link)
+	assert.Equal(t, "ks-releaser/test", requests["POST /api/v5/repos/o/r/pulls"].body["head"])
+
+	// reuse the existing pull request
+	handlers["GET /api/v5/repos/o/r/pulls"] = []map[string]interface{}{{
+		"number": 1, "state": "open", "html_url": "https://gitee.com/o/r/pulls/1",
+		"head": map[string]string{"ref": "ks-releaser/test"}, "base": map[string]string{"ref": "master"},
+	}}
+	delete(requests, "POST /api/v5/repos/o/r/pulls")
+	number, _, err = gitee.CreatePullRequest("title", "body", "ks-releaser/test", "master")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, number)
+	assert.NotContains(t, requests, "POST /api/v5/repos/o/r/pulls")
+
+	// wait until it is mergeable
+	merged, err := gitee.MergePullRequest(1)
+	assert.Nil(t, err)
+	assert.False(t, merged)
+
+	handlers["GET /api/v5/repos/o/r/pulls/1"] = map[string]interface{}{"number": 1, "state": "open", "mergeable": true}
+	handlers["PUT /api/v5/repos/o/r/pulls/1/merge"] = map[string]interface{}{"merged": true}
+	merged, err = gitee.MergePullRequest(1)
+	assert.Nil(t, err)
+	assert.True(t, merged)
+
+	handlers["GET /api/v5/repos/o/r/pulls/1"] = map[string]interface{}{"number": 1, "state": "closed"}
+	_, err = gitee.MergePullRequest(1)
+	assert.NotNil(t, err)
+}
+
+func TestGitee_CloseIssue(t *testing.T) {
+	handlers := map[string]interface{}{
+		"GET /api/v5/repos/o/r/issues": []map[string]interface{}{},
+	}
+	server, requests := newFakeServer(t, handlers)
+	gitee := NewGitee(server.URL, "o/r", "token")
+
+	number, err := gitee.CloseIssue(IssueRef{Title: "releaser"}, "resolved")
+	assert.Nil(t, err)
+	assert.Empty(t, number)
+	assert.NotNil(t, gitee.LabelIssue("I1", []string{"release-failure"}))
+
+	handlers["GET /api/v5/repos/o/r/issues"] = []map[string]interface{}{{"number": "I1", "title": "releaser"}}
+	handlers["POST /api/v5/repos/o/r/issues/I1/labels"] = []map[string]interface{}{{"name": "release-failure"}}
+	handlers["POST /api/v5/repos/o/r/issues/I1/comments"] = map[string]interface{}{"id": 1}
+	handlers["PATCH /api/v5/repos/o/issues/I1"] = map[string]interface{}{"number": "I1"}
+	err = gitee.LabelIssue("I1", []string{"release-failure"})
+	assert.Nil(t, err)
+	assert.Contains(t, requests, "POST /api/v5/repos/o/r/issues/I1/labels")
+
+	number, err = gitee.CloseIssue(IssueRef{Title: "releaser"}, "resolved")
+	assert.Nil(t, err)
+	assert.Equal(t, "I1", number)
+	assert.Equal(t, "resolved", requests["POST /api/v5/repos/o/r/issues/I1/comments"].body["body"])
+	assert.Equal(t, map[string]interface{}{
+		"repo":  "r",
+		"state": "closed",
+	}, requests["PATCH /api/v5/repos/o/issues/I1"].body)
+}
+
+func TestGitee_FindIssue(t *testing.T) {
+	handlers := map[string]interface{}{
+		"GET /api/v5/repos/o/r/issues": []map[string]interface{}{
+			{"number": "I2", "title": "releaser", "state": "open"},
+			{"number": "I1", "title": "renamed", "body": "failed <!-- releaser -->", "state": "progressing"},
+		},
+		"GET /api/v5/repos/o/r/issues/I3": map[string]interface{}{"number": "I3", "state": "open"},
+		"GET /api/v5/repos/o/r/issues/I4": map[string]interface{}{"number": "I4", "state": "closed"},
+	}
+	server, requests := newFakeServer(t, handlers)
+	gitee := NewGitee(server.URL, "o/r", "token")
+	client := gitee.getRESTClient()
+
+	// find the issue by the marker instead of the title
+	issue, err := gitee.findIssue(client, IssueRef{Title: "releaser", Marker: "<!-- releaser -->"})
+	assert.Nil(t, err)
+	assert.Equal(t, "I1", issue.Number)
+
+	// find the issue by its number without searching
+	delete(requests, "GET /api/v5/repos/o/r/issues")
+	issue, err = gitee.findIssue(client, IssueRef{Number: "I3", Marker: "<!-- releaser -->"})
+	assert.Nil(t, err)
+	assert.Equal(t, "I3", issue.Number)
+	assert.NotContains(t, requests, "GET /api/v5/repos/o/r/issues")
+
+	// the numbered issue was closed
+	issue, err = gitee.findIssue(client, IssueRef{Number: "I4", Marker: "<!-- other -->"})
+	assert.Nil(t, err)
+	assert.Nil(t, issue)
+
+	// failed to find the numbered issue
+	handlers["GET /api/v5/repos/o/r/issues/I5"] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	_, err = gitee.findIssue(client, IssueRef{Number: "I5", Marker: "<!-- releaser -->"})
+	assert.NotNil(t, err)
+
+	// the issue which was created before the markers is on the second page
+	handlers["GET /api/v5/repos/o/r/issues"] = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		list := make([]map[string]interface{}, 0, issuePageSize)
+		if req.URL.Query().Get("page") == "1" {
+			for i := 0; i < issuePageSize; i++ {
+				list = append(list, map[string]interface{}{"number": fmt.Sprintf("O%d", i), "title": "other"})
+			}
+		} else {
+			list = append(list, map[string]interface{}{"number": "I2", "title": "releaser"})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	})
+	issue, err = gitee.findIssue(client, IssueRef{Title: "releaser", Marker: "<!-- ks-releaser: ns/releaser -->"})
+	assert.Nil(t, err)
+	assert.Equal(t, "I2", issue.Number)
+}
@@ -1,61 +1,97 @@
 package internal_scm
 
 import (
-	"context"
 	"fmt"
 	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/driver/github"
 	"github.com/jenkins-x/go-scm/scm/transport"
 	"net/http"
+	"strings"
 )
 
+const githubServer = "https://github.com"
+
 type GitHub struct {
-	repo  string
-	token string
+	server string
+	repo   string
+	token  string
 }
 
-// NewGitHub creates a new instance
-func NewGitHub(repo, token string) *GitHub {
+// NewGitHub creates a new instance, the server is the address of a GitHub Enterprise, or empty for github.com
+func NewGitHub(server, repo, token string) *GitHub {
 	return &GitHub{
-		repo:  repo,
-		token: token,
+		server: strings.TrimSuffix(server, "/"),
+		repo:   repo,
+		token:  token,
 	}
 }
 
-func (r *GitHub) Release(version, commitish string, draft, prerelease bool) (err error) {
-	client := github.NewDefault()
+func (r *GitHub) getClient() (client *scm.Client, err error) {
+	if r.server == "" || r.server == githubServer {
+		client = github.NewDefault()
+	} else {
+		// the REST API of GitHub Enterprise is under /api/v3
+		api := r.server
+		if !strings.HasSuffix(api, "/api/v3") {
+			api = api + "/api/v3"
+		}
+		if client, err = github.New(api); err != nil {
+			err = fmt.Errorf("failed to create github client, error: %v", err)
+			return
+		}
+	}
 	client.Client = &http.Client{
 		Transport: &transport.BearerToken{
 			Token: r.token,
 		},
 	}
-	err = release(client, r.repo, version, commitish, draft, prerelease)
 	return
 }
 
-func (r *GitHub) CreateIssue(title, body string) (err error) {
-	ctx := context.TODO()
-	client := github.NewDefault()
-	client.Client = &http.Client{
-		Transport: &transport.BearerToken{
-			Token: r.token,
-		},
+func (r *GitHub) Release(version, commitish, description string, draft, prerelease bool) (link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		link, err = release(client, r.repo, version, commitish, description, draft, prerelease)
 	}
+	return
+}
 
-	var list []*scm.SearchIssue
-	if list, _, err = client.Issues.Search(ctx, scm.SearchOptions{
-		Query: fmt.Sprintf("%s in:title repo:%s", title, r.repo),
-	}); err == nil && len(list) > 0 {
-		issue := list[0]
+func (r *GitHub) CreateIssue(issue IssueRef, body string) (number string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, err = createIssue(client, r.repo, issue, body)
+	}
+	return
+}
 
-		_, _, err = client.Issues.CreateComment(ctx, r.repo, issue.Number, &scm.CommentInput{
-			Body: body,
-		})
-	} else {
-		_, _, err = client.Issues.Create(ctx, r.repo, &scm.IssueInput{
-			Title: title,
-			Body:  body,
-		})
+func (r *GitHub) CloseIssue(issue IssueRef, comment string) (number string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, err = closeIssue(client, r.repo, issue, comment)
+	}
+	return
+}
+
+func (r *GitHub) LabelIssue(number string, labels []string) (err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		err = labelIssue(client, r.repo, number, labels)
+	}
+	return
+}
+
+func (r *GitHub) CreatePullRequest(title, body, head, base string) (number int, link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, link, err = createPullRequest(client, r.repo, title, body, head, base)
+	}
+	return
+}
+
+func (r *GitHub) MergePullRequest(number int) (merged bool, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		merged, err = mergePullRequest(client, r.repo, number)
 	}
 	return
 }
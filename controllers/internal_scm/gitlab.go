@@ -2,35 +2,89 @@ package internal_scm
 
 import (
 	"fmt"
+	"github.com/jenkins-x/go-scm/scm"
 	"github.com/jenkins-x/go-scm/scm/driver/gitlab"
 	"github.com/jenkins-x/go-scm/scm/transport"
 	"net/http"
+	"strings"
 )
 
+const gitlabServer = "https://gitlab.com"
+
 type Gitlab struct {
-	repo  string
-	token string
+	server string
+	repo   string
+	token  string
 }
 
-// NewGitlab creates a new instance
-func NewGitlab(repo, token string) *Gitlab {
+// NewGitlab creates a new instance, the server is the address of a self-managed GitLab, or empty for gitlab.com
+func NewGitlab(server, repo, token string) *Gitlab {
 	return &Gitlab{
-		repo:  repo,
-		token: token,
+		server: strings.TrimSuffix(server, "/"),
+		repo:   repo,
+		token:  token,
 	}
 }
 
-func (r *Gitlab) Release(version, commitish string, draft, prerelease bool) (err error) {
-	client := gitlab.NewDefault()
+func (r *Gitlab) getClient() (client *scm.Client, err error) {
+	if r.server == "" || r.server == gitlabServer {
+		client = gitlab.NewDefault()
+	} else if client, err = gitlab.New(r.server); err != nil {
+		err = fmt.Errorf("failed to create gitlab client, error: %v", err)
+		return
+	}
 	client.Client = &http.Client{
 		Transport: &transport.BearerToken{
 			Token: r.token,
 		},
 	}
-	err = release(client, r.repo, version, commitish, draft, prerelease)
 	return
 }
 
-func (r *Gitlab) CreateIssue(title, body string) (err error) {
-	return fmt.Errorf("not support yet")
+func (r *Gitlab) Release(version, commitish, description string, draft, prerelease bool) (link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		link, err = release(client, r.repo, version, commitish, description, draft, prerelease)
+	}
+	return
+}
+
+func (r *Gitlab) CreateIssue(issue IssueRef, body string) (number string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, err = createIssue(client, r.repo, issue, body)
+	}
+	return
+}
+
+func (r *Gitlab) CloseIssue(issue IssueRef, comment string) (number string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, err = closeIssue(client, r.repo, issue, comment)
+	}
+	return
+}
+
+func (r *Gitlab) LabelIssue(number string, labels []string) (err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		err = labelIssue(client, r.repo, number, labels)
+	}
+	return
+}
+
+func (r *Gitlab) CreatePullRequest(title, body, head, base string) (number int, link string, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		number, link, err = createPullRequest(client, r.repo, title, body, head, base)
+	}
+	return
+}
+
+func (r *Gitlab) MergePullRequest(number int) (merged bool, err error) {
+	var client *scm.Client
+	if client, err = r.getClient(); err == nil {
+		merged, err = mergePullRequest(client, r.repo, number)
+	}
+	return
 }
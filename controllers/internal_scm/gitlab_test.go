@@ -0,0 +1,97 @@
+package internal_scm
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestGitlab_CreateIssue(t *testing.T) {
+	handlers := map[string]interface{}{
+		"GET /api/v4/projects/o/r/issues":  []map[string]interface{}{},
+		"POST /api/v4/projects/o/r/issues": map[string]interface{}{"iid": 1, "title": "releaser"},
+	}
+	server, requests := newFakeServer(t, handlers)
+	gitlab := NewGitlab(server.URL, "o/r", "token")
+
+	number, err := gitlab.CreateIssue(IssueRef{Title: "releaser"}, "failed")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, "opened", requests["GET /api/v4/projects/o/r/issues"].query.Get("state"))
+	assert.Equal(t, "releaser", requests["POST /api/v4/projects/o/r/issues"].query.Get("title"))
+	assert.Equal(t, "failed", requests["POST /api/v4/projects/o/r/issues"].query.Get("description"))
+
+	// comment on the existing issue
+	handlers["GET /api/v4/projects/o/r/issues"] = []map[string]interface{}{
+		{"iid": 2, "title": "other", "state": "opened"},
+		{"iid": 1, "title": "releaser", "state": "opened"},
+	}
+	handlers["POST /api/v4/projects/o/r/issues/1/notes"] = map[string]interface{}{"id": 1, "body": "failed again"}
+	delete(requests, "POST /api/v4/projects/o/r/issues")
+	number, err = gitlab.CreateIssue(IssueRef{Title: "releaser"}, "failed again")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, "failed again", requests["POST /api/v4/projects/o/r/issues/1/notes"].query.Get("body"))
+	assert.NotContains(t, requests, "POST /api/v4/projects/o/r/issues")
+
+	// failed to list the issues
+	delete(handlers, "GET /api/v4/projects/o/r/issues")
+	_, err = gitlab.CreateIssue(IssueRef{Title: "releaser"}, "failed")
+	assert.NotNil(t, err)
+}
+
+func TestGitlab_CloseIssue(t *testing.T) {
+	handlers := map[string]interface{}{
+		"GET /api/v4/projects/o/r/issues": []map[string]interface{}{},
+	}
+	server, requests := newFakeServer(t, handlers)
+	gitlab := NewGitlab(server.URL, "o/r", "token")
+
+	// there is no open issue
+	number, err := gitlab.CloseIssue(IssueRef{Title: "releaser"}, "resolved")
+	assert.Nil(t, err)
+	assert.Empty(t, number)
+	assert.NotNil(t, gitlab.LabelIssue("invalid", []string{"release-failure"}))
+
+	handlers["GET /api/v4/projects/o/r/issues"] = []map[string]interface{}{
+		{"iid": 1, "title": "releaser", "state": "opened"},
+	}
+	handlers["GET /api/v4/projects/o/r/issues/1"] = map[string]interface{}{"iid": 1, "labels": []string{}}
+	handlers["PUT /api/v4/projects/o/r/issues/1"] = map[string]interface{}{"iid": 1}
+	err = gitlab.LabelIssue("1", []string{"release-failure"})
+	assert.Nil(t, err)
+	assert.Contains(t, requests, "PUT /api/v4/projects/o/r/issues/1")
+
+	handlers["POST /api/v4/projects/o/r/issues/1/notes"] = map[string]interface{}{"id": 1}
+	number, err = gitlab.CloseIssue(IssueRef{Title: "releaser"}, "resolved")
+	assert.Nil(t, err)
+	assert.Equal(t, "1", number)
+	assert.Equal(t, "resolved", requests["POST /api/v4/projects/o/r/issues/1/notes"].query.Get("body"))
+	assert.Equal(t, "close", requests["PUT /api/v4/projects/o/r/issues/1"].query.Get("state_event"))
+}
+
+func TestGitlab_FindIssue(t *testing.T) {
+	handlers := map[string]interface{}{
+		"GET /api/v4/projects/o/r/issues": []map[string]interface{}{
+			{"iid": 1, "title": "releaser", "state": "opened"},
+		},
+		"GET /api/v4/projects/o/r/issues/2": map[string]interface{}{"iid": 2, "title": "other", "state": "opened"},
+		"GET /api/v4/projects/o/r/issues/3": map[string]interface{}{"iid": 3, "title": "releaser", "state": "closed"},
+	}
+	server, requests := newFakeServer(t, handlers)
+	client, err := NewGitlab(server.URL, "o/r", "token").getClient()
+	assert.Nil(t, err)
+
+	// find the issue by its number without searching
+	issue, err := findIssue(client, "o/r", IssueRef{Number: "2", Title: "releaser"})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, issue.Number)
+	assert.NotContains(t, requests, "GET /api/v4/projects/o/r/issues")
+
+	// the numbered issue was closed, fall back to the title
+	issue, err = findIssue(client, "o/r", IssueRef{Number: "3", Title: "releaser"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, issue.Number)
+
+	_, err = findIssue(client, "o/r", IssueRef{Number: "invalid", Title: "releaser"})
+	assert.NotNil(t, err)
+}
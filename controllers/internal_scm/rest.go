@@ -0,0 +1,99 @@
+package internal_scm
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// errNotFound indicates that the resource does not exist
+var errNotFound = errors.New("not found")
+
+// restClient sends the JSON requests to the REST API which is not covered by go-scm
+type restClient struct {
+	baseURL string
+	// authorize puts the token into the request
+	authorize func(req *http.Request)
+}
+
+func newBearerRESTClient(baseURL, token string) *restClient {
+	return &restClient{
+		baseURL: baseURL,
+		authorize: func(req *http.Request) {
+			if token != "" {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+		},
+	}
+}
+
+func newBasicRESTClient(baseURL, username, password string) *restClient {
+	return &restClient{
+		baseURL: baseURL,
+		authorize: func(req *http.Request) {
+			req.SetBasicAuth(username, password)
+		},
+	}
+}
+
+func (c *restClient) do(method, path string, query url.Values, in, out interface{}) (err error) {
+	api := strings.TrimSuffix(c.baseURL, "/") + "/" + strings.TrimPrefix(path, "/")
+	if len(query) > 0 {
+		api = api + "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if in != nil {
+		var data []byte
+		if data, err = json.Marshal(in); err != nil {
+			return
+		}
+		body = bytes.NewReader(data)
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequest(method, api, body); err != nil {
+		return
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.authorize != nil {
+		c.authorize(req)
+	}
+
+	var resp *http.Response
+	if resp, err = http.DefaultClient.Do(req); err != nil {
+		// the URL is not reported because the token might be in the query
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = fmt.Errorf("failed to send request %s %s, error: %v", method, path, urlErr.Err)
+		}
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		err = errNotFound
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		data, _ := ioutil.ReadAll(resp.Body)
+		err = fmt.Errorf("unexpected status code %d of %s %s, response: %s", resp.StatusCode, method, path,
+			strings.TrimSpace(string(data)))
+	case out != nil:
+		var data []byte
+		if data, err = ioutil.ReadAll(resp.Body); err == nil && len(bytes.TrimSpace(data)) > 0 {
+			err = json.Unmarshal(data, out)
+		}
+	}
+	return
+}
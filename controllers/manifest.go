@@ -0,0 +1,206 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// defaultUpdateFields are the fields which will be updated if there are no fields in a GitOps update
+var defaultUpdateFields = map[devopsv1alpha1.GitOpsUpdateKind][]string{
+	devopsv1alpha1.GitOpsUpdateHelmChart:  {"version", "appVersion"},
+	devopsv1alpha1.GitOpsUpdateHelmValues: {"image.tag"},
+}
+
+// yamlPathSegmentPattern matches a segment of a YAML path, such as: containers[0]
+var yamlPathSegmentPattern = regexp.MustCompile(`^([^\[\]]*)((?:\[\d+\])*)$`)
+
+// updateGitOpsFiles updates the files of the GitOps repository with the released versions
+func updateGitOpsFiles(repoDir string, releaser *devopsv1alpha1.Releaser) (err error) {
+	for _, update := range releaser.Spec.GitOps.Updates {
+		filePath := filepath.Join(repoDir, update.Path)
+		if !strings.HasPrefix(filePath, filepath.Clean(repoDir)+string(filepath.Separator)) {
+			err = fmt.Errorf("file %s is out of the GitOps repository", update.Path)
+			return
+		}
+
+		var data []byte
+		if data, err = ioutil.ReadFile(filePath); err != nil {
+			err = fmt.Errorf("failed to read file %s, error: %v", update.Path, err)
+			return
+		}
+		if data, err = updateManifest(data, update, getUpdateVersion(releaser, update)); err != nil {
+			err = fmt.Errorf("failed to update file %s, error: %v", update.Path, err)
+			return
+		}
+		if err = ioutil.WriteFile(filePath, data, 0644); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// getUpdateVersion returns the version of the repository which the update refers to, or the version of the Releaser
+func getUpdateVersion(releaser *devopsv1alpha1.Releaser, update devopsv1alpha1.GitOpsUpdate) string {
+	if update.Repository != "" {
+		for _, repo := range releaser.Spec.Repositories {
+			if repo.Name == update.Repository {
+				return repo.Version
+			}
+		}
+	}
+	return releaser.Spec.Version
+}
+
+// updateManifest updates all the YAML documents of a file in place, so the format of it will be kept.
+// It fails if nothing was updated.
+func updateManifest(data []byte, update devopsv1alpha1.GitOpsUpdate, version string) (result []byte, err error) {
+	var edits []yamlEdit
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		doc := &yaml.Node{}
+		if err = decoder.Decode(doc); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+
+		var docEdits []yamlEdit
+		if docEdits, err = updateDocument(data, doc, update, version); err != nil {
+			return
+		}
+		edits = append(edits, docEdits...)
+	}
+
+	if len(edits) == 0 {
+		err = fmt.Errorf("cannot find the fields to update")
+		return
+	}
+	result = applyYAMLEdits(data, edits)
+	return
+}
+
+// updateDocument returns the edits of a YAML document, the positions of the nodes are in the whole data
+func updateDocument(data []byte, doc *yaml.Node, update devopsv1alpha1.GitOpsUpdate, version string) (edits []yamlEdit, err error) {
+	if len(doc.Content) == 0 {
+		return
+	}
+	root := doc.Content[0]
+
+	if update.Kind == devopsv1alpha1.GitOpsUpdateKustomization {
+		edits, err = updateKustomizationImages(data, root, update.Image, version)
+		return
+	}
+
+	fields := update.Fields
+	if len(fields) == 0 {
+		fields = defaultUpdateFields[update.Kind]
+	}
+	for _, field := range fields {
+		value := version
+		if update.Kind == devopsv1alpha1.GitOpsUpdateHelmChart && field == "version" {
+			// the version of a chart must be a SemVer without the prefix
+			value = strings.TrimPrefix(version, "v")
+		}
+
+		var node *yaml.Node
+		if node, err = findYAMLNode(root, field); err != nil {
+			return
+		} else if node != nil {
+			var edit yamlEdit
+			if edit, err = newScalarEdit(data, node, value); err != nil {
+				err = fmt.Errorf("cannot update field %s, error: %v", field, err)
+				return
+			}
+			edits = append(edits, edit)
+		}
+	}
+	return
+}
+
+// updateKustomizationImages sets the newTag of the image, it will be added if it does not exist
+func updateKustomizationImages(data []byte, root *yaml.Node, name, version string) (edits []yamlEdit, err error) {
+	if name == "" {
+		err = fmt.Errorf("the image is required by kind %s", devopsv1alpha1.GitOpsUpdateKustomization)
+		return
+	}
+	images := getMappingValue(root, "images")
+	if images == nil || images.Kind != yaml.SequenceNode {
+		return
+	}
+
+	for _, image := range images.Content {
+		if imageName := getMappingValue(image, "name"); imageName == nil || imageName.Value != name {
+			continue
+		}
+
+		var edit yamlEdit
+		if newTag := getMappingValue(image, "newTag"); newTag != nil {
+			edit, err = newScalarEdit(data, newTag, version)
+		} else {
+			edit, err = newMappingEntryEdit(data, image, "newTag", version)
+		}
+		if err != nil {
+			err = fmt.Errorf("cannot update the newTag of image %s, error: %v", name, err)
+			return
+		}
+		edits = append(edits, edit)
+	}
+	return
+}
+
+// findYAMLNode returns the scalar node of a path, such as: spec.template.spec.containers[0].env[0].value.
+// The node is nil if the path does not exist.
+func findYAMLNode(root *yaml.Node, path string) (node *yaml.Node, err error) {
+	node = root
+	for _, segment := range strings.Split(path, ".") {
+		groups := yamlPathSegmentPattern.FindStringSubmatch(segment)
+		if groups == nil || (groups[1] == "" && groups[2] == "") {
+			err = fmt.Errorf("invalid YAML path: %s", path)
+			return
+		}
+
+		if key := groups[1]; key != "" {
+			if node = getMappingValue(node, key); node == nil {
+				return
+			}
+		}
+		for _, index := range strings.Split(strings.Trim(groups[2], "[]"), "][") {
+			if index == "" {
+				continue
+			}
+			i, _ := strconv.Atoi(index)
+			if node.Kind != yaml.SequenceNode || i >= len(node.Content) {
+				node = nil
+				return
+			}
+			node = node.Content[i]
+		}
+	}
+
+	if node.Kind != yaml.ScalarNode {
+		err = fmt.Errorf("the value of %s is not a scalar", path)
+	}
+	return
+}
+
+// getMappingValue returns the value node of the key, it returns nil if the node is not a mapping or the key is missing
+func getMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"path"
+	"testing"
+)
+
+func Test_updateManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		update  v1alpha1.GitOpsUpdate
+		want    string
+		wantErr bool
+	}{{
+		name: "kustomization image",
+		data: `images:
+  - name: kubesphere/ks-apiserver
+    newTag: v1.0.0
+  - name: kubesphere/ks-console
+    newTag: v1.0.0
+`,
+		update: v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateKustomization, Image: "kubesphere/ks-console"},
+		want: `images:
+  - name: kubesphere/ks-apiserver
+    newTag: v1.0.0
+  - name: kubesphere/ks-console
+    newTag: v1.1.0
+`,
+	}, {
+		name: "kustomization image without newTag",
+		data: `images:
+  - name: kubesphere/ks-apiserver # the apiserver
+
+  - name: kubesphere/ks-console
+`,
+		update: v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateKustomization, Image: "kubesphere/ks-apiserver"},
+		want: `images:
+  - name: kubesphere/ks-apiserver # the apiserver
+    newTag: v1.1.0
+
+  - name: kubesphere/ks-console
+`,
+	}, {
+		name:   "kustomization image in a flow mapping",
+		data:   "images: [{name: kubesphere/ks-console}, {name: kubesphere/ks-apiserver, newTag: 'v1.0.0'}]\n",
+		update: v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateKustomization, Image: "kubesphere/ks-console"},
+		want:   "images: [{name: kubesphere/ks-console, newTag: v1.1.0}, {name: kubesphere/ks-apiserver, newTag: 'v1.0.0'}]\n",
+	}, {
+		name:    "kustomization without the image name",
+		data:    "images:\n  - name: kubesphere/ks-console\n",
+		update:  v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateKustomization},
+		wantErr: true,
+	}, {
+		name:    "kustomization without the image",
+		data:    "images: []\n",
+		update:  v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateKustomization, Image: "fake"},
+		wantErr: true,
+	}, {
+		name: "helm chart",
+		data: `apiVersion: v2
+name: ks-console # the name of chart
+version: 1.0.0
+appVersion: "v1.0.0"
+`,
+		update: v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateHelmChart},
+		want: `apiVersion: v2
+name: ks-console # the name of chart
+version: 1.1.0
+appVersion: "v1.1.0"
+`,
+	}, {
+		name: "helm values",
+		data: `image:
+  repository: kubesphere/ks-console
+  tag: v1.0.0
+`,
+		update: v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateHelmValues},
+		want: `image:
+  repository: kubesphere/ks-console
+  tag: v1.1.0
+`,
+	}, {
+		name: "generic YAML path in multiple documents",
+		data: `kind: Service
+---
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: console
+          env:
+            - name: VERSION
+              value: v1.0.0
+`,
+		update: v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateYAML,
+			Fields: []string{"spec.template.spec.containers[0].env[0].value"}},
+		want: `kind: Service
+---
+kind: Deployment
+spec:
+  template:
+    spec:
+      containers:
+        - name: console
+          env:
+            - name: VERSION
+              value: v1.1.0
+`,
+	}, {
+		name: "keep the format",
+		data: `# the values of console
+image:
+    repository:   kubesphere/ks-console
+
+    tag: "v1.0.0"   # the tag
+resources: {}
+`,
+		update: v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateHelmValues},
+		want: `# the values of console
+image:
+    repository:   kubesphere/ks-console
+
+    tag: "v1.1.0"   # the tag
+resources: {}
+`,
+	}, {
+		name:    "not a scalar",
+		data:    "image:\n  tag: v1.0.0\n",
+		update:  v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateYAML, Fields: []string{"image"}},
+		wantErr: true,
+	}, {
+		name:    "invalid path",
+		data:    "image:\n  tag: v1.0.0\n",
+		update:  v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateYAML, Fields: []string{"image..tag"}},
+		wantErr: true,
+	}, {
+		name:    "missing field",
+		data:    "image:\n  tag: v1.0.0\n",
+		update:  v1alpha1.GitOpsUpdate{Kind: v1alpha1.GitOpsUpdateYAML, Fields: []string{"image.name"}},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := updateManifest([]byte(tt.data), tt.update, "v1.1.0")
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(result))
+		})
+	}
+}
+
+func Test_updateGitOpsFiles(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(path.Join(dir, "values.yaml"), []byte("image:\n  tag: v1.0.0\n"), 0644)
+	assert.Nil(t, err)
+
+	releaser := &v1alpha1.Releaser{Spec: v1alpha1.ReleaserSpec{
+		Version:      "v1.1.0",
+		Repositories: []v1alpha1.Repository{{Name: "console", Version: "v1.1.1"}},
+		GitOps: &v1alpha1.GitOps{Updates: []v1alpha1.GitOpsUpdate{{
+			Path: "values.yaml", Kind: v1alpha1.GitOpsUpdateHelmValues, Repository: "console",
+		}}},
+	}}
+	err = updateGitOpsFiles(dir, releaser)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadFile(path.Join(dir, "values.yaml"))
+	assert.Nil(t, err)
+	assert.Equal(t, "image:\n  tag: v1.1.1\n", string(data))
+
+	// out of the repository
+	releaser.Spec.GitOps.Updates[0].Path = "../values.yaml"
+	assert.NotNil(t, updateGitOpsFiles(dir, releaser))
+}
@@ -0,0 +1,139 @@
+package controllers
+
+import (
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+// needToPlan checks if the release plan is missing or out of date
+func needToPlan(releaser *devopsv1alpha1.Releaser) bool {
+	plan := releaser.Status.Plan
+	return plan == nil || plan.ObservedGeneration != releaser.Generation
+}
+
+// plan makes a release plan of the Releaser, nothing will be pushed
+func (r *ReleaserReconciler) plan(releaser *devopsv1alpha1.Releaser, secret *v1.Secret) (plan *devopsv1alpha1.ReleasePlan) {
+	plan = &devopsv1alpha1.ReleasePlan{
+		ObservedGeneration: releaser.Generation,
+		PlanTime:           &metav1.Time{Time: time.Now()},
+	}
+
+	// the next versions are unknown if they cannot be bumped
+	var nextReleaser *devopsv1alpha1.Releaser
+	var bumpErr error
+	if hasNextReleaser(releaser) {
+		nextReleaser = releaser.DeepCopy()
+		var isPre bool
+		if isPre, bumpErr = bumpReleaser(nextReleaser, true, r.getClock()); bumpErr == nil {
+			plan.NextReleasers = append(plan.NextReleasers, nextReleaser.Name)
+		}
+
+		if bumpErr == nil && isPre {
+			nextReleaserWithoutPre := releaser.DeepCopy()
+			if _, bumpErr = bumpReleaser(nextReleaserWithoutPre, false, r.getClock()); bumpErr == nil {
+				plan.NextReleasers = append(plan.NextReleasers, nextReleaserWithoutPre.Name)
+			}
+		}
+	}
+
+	// the stages are unknown if there are invalid dependencies
+	repoStages := make(map[int]int)
+	stages, stagesErr := releaser.Spec.GetReleaseStages()
+	for i, stage := range stages {
+		for _, index := range stage {
+			repoStages[index] = i
+		}
+	}
+
+	option := r.newReleaseOption(releaser, secret)
+	repos := releaser.Spec.Repositories
+	plan.Repositories = make([]devopsv1alpha1.RepositoryPlan, len(repos))
+	runConcurrently(len(repos), r.getConcurrency(releaser), func(index int) {
+		repoPlan := &plan.Repositories[index]
+		if err := planRepository(repos[index], option, repoPlan); err != nil {
+			repoPlan.Error = err.Error()
+		} else if stagesErr != nil {
+			repoPlan.Error = stagesErr.Error()
+		} else if bumpErr != nil {
+			repoPlan.Error = fmt.Sprintf("failed to bump the next versions, error: %v", bumpErr)
+		}
+		repoPlan.Stage = repoStages[index]
+		if nextReleaser != nil && bumpErr == nil {
+			repoPlan.NextVersion = nextReleaser.Spec.Repositories[index].Version
+		}
+	})
+	return
+}
+
+// planRepository finds out the commit which would be tagged, and checks if the tag exists already
+func planRepository(repo devopsv1alpha1.Repository, option releaseOption, repoPlan *devopsv1alpha1.RepositoryPlan) (err error) {
+	*repoPlan = devopsv1alpha1.RepositoryPlan{
+		Name:    repo.Name,
+		Address: repo.Address,
+		Branch:  repo.Branch,
+		Version: repo.Version,
+		Action:  getAction(repo, option.scheme),
+	}
+
+	unlock := lockRepoCacheDir(option.cacheDir, repo.Address)
+	defer unlock()
+
+	var gitRepo *git.Repository
+	if gitRepo, err = clone(repo.Address, repo.Branch, getAuth(option.secret), option.cacheDir); err != nil {
+		err = fmt.Errorf("failed to clone %s, error: %v", repo.Address, err)
+		return
+	}
+
+	var commit plumbing.Hash
+	if commit, err = getTagTarget(gitRepo, repo.Commit, repo.Branch); err != nil {
+		err = fmt.Errorf("failed to find the commit to tag from %s, error: %v", repo.Address, err)
+		return
+	}
+	repoPlan.Commit = commit.String()
+	if repoPlan.TagExists, err = checkExistingTag(gitRepo, repo.Version, commit, repo.OnExistingTag); err != nil {
+		return
+	}
+
+	if len(repo.VersionFiles) > 0 {
+		if err = planVersionFiles(gitRepo, repo, commit, repoPlan); err != nil {
+			return
+		}
+	}
+
+	if option.createBranch {
+		repoPlan.ReleaseBranch, err = getReleaseBranchName(option.scheme, repo.Version)
+	}
+	return
+}
+
+// planVersionFiles checks if the version files are able to be updated, nothing will be written
+func planVersionFiles(r *git.Repository, repo devopsv1alpha1.Repository, target plumbing.Hash,
+	repoPlan *devopsv1alpha1.RepositoryPlan) (err error) {
+	var head *plumbing.Reference
+	if head, err = r.Head(); err != nil {
+		return
+	}
+	if head.Hash() != target {
+		err = fmt.Errorf("cannot update the version files when tagging commit %s which is not the head of branch %s",
+			target.String(), repo.Branch)
+		return
+	}
+
+	var wd *git.Worktree
+	if wd, err = r.Worktree(); err != nil {
+		return
+	}
+	for _, versionFile := range repo.VersionFiles {
+		if _, _, err = replaceVersionFile(wd.Filesystem.Root(), versionFile, repo.Version); err != nil {
+			err = fmt.Errorf("failed to update version file %s, error: %v", versionFile.Path, err)
+			return
+		}
+		repoPlan.VersionFiles = append(repoPlan.VersionFiles, versionFile.Path)
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"testing"
+	"time"
+)
+
+func Test_needToPlan(t *testing.T) {
+	releaser := &devopsv1alpha1.Releaser{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+	assert.True(t, needToPlan(releaser))
+
+	releaser.Status.Plan = &devopsv1alpha1.ReleasePlan{ObservedGeneration: 1}
+	assert.True(t, needToPlan(releaser))
+
+	releaser.Status.Plan.ObservedGeneration = 2
+	assert.False(t, needToPlan(releaser))
+}
+
+func TestReleaserReconciler_plan(t *testing.T) {
+	dir, repo := newLocalRepository(t, "first commit", "second commit")
+	head, err := repo.Head()
+	assert.Nil(t, err)
+	_, err = repo.CreateTag("v0.0.1", head.Hash(), &git.CreateTagOptions{
+		Tagger:  &object.Signature{Name: "test", Email: "test@test.com", When: time.Now()},
+		Message: "v0.0.1",
+	})
+	assert.Nil(t, err)
+
+	r := &ReleaserReconciler{GitCacheDir: t.TempDir()}
+	plan := r.plan(&devopsv1alpha1.Releaser{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-v0.0.1-rc.0", Generation: 1},
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Version: "v0.0.1-rc.0",
+			Repositories: []devopsv1alpha1.Repository{{
+				Name:    "existing",
+				Address: dir,
+				Branch:  "master",
+				Version: "v0.0.1",
+				Action:  devopsv1alpha1.ActionTag,
+			}, {
+				Name:    "new",
+				Address: dir,
+				Branch:  "master",
+				Version: "v0.0.2",
+				Action:  devopsv1alpha1.ActionTag,
+			}, {
+				Name:    "invalid",
+				Address: dir,
+				Branch:  "invalid",
+				Version: "v0.0.2",
+			}},
+		},
+	}, nil)
+
+	assert.Equal(t, int64(1), plan.ObservedGeneration)
+	assert.Equal(t, []string{"test-v0.0.1-rc.1", "test-v0.0.1"}, plan.NextReleasers)
+	assert.Equal(t, 3, len(plan.Repositories))
+
+	existing := plan.Repositories[0]
+	assert.True(t, existing.TagExists)
+	assert.Equal(t, head.Hash().String(), existing.Commit)
+	assert.Equal(t, "v0.0.2", existing.NextVersion)
+	assert.Empty(t, existing.Error)
+
+	newTag := plan.Repositories[1]
+	assert.False(t, newTag.TagExists)
+	assert.Equal(t, head.Hash().String(), newTag.Commit)
+	assert.Equal(t, "v0.0.3", newTag.NextVersion)
+
+	assert.NotEmpty(t, plan.Repositories[2].Error)
+}
+
+func TestReleaserReconciler_planWithoutNextReleaser(t *testing.T) {
+	dir, _ := newLocalRepository(t, "first commit")
+
+	r := &ReleaserReconciler{GitCacheDir: t.TempDir()}
+	plan := r.plan(&devopsv1alpha1.Releaser{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-v0.0.1-rc.0"},
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Version:     "v0.0.1-rc.0",
+			NextVersion: &devopsv1alpha1.NextVersion{Strategy: devopsv1alpha1.BumpStrategyNone},
+			Repositories: []devopsv1alpha1.Repository{{
+				Address: dir,
+				Branch:  "master",
+				Version: "v0.0.1-rc.0",
+				Action:  devopsv1alpha1.ActionTag,
+			}},
+		},
+	}, nil)
+
+	assert.Empty(t, plan.NextReleasers)
+	assert.Equal(t, 1, len(plan.Repositories))
+	assert.Empty(t, plan.Repositories[0].NextVersion)
+	assert.Empty(t, plan.Repositories[0].Error)
+}
+
+func TestReleaserReconciler_planVersionFiles(t *testing.T) {
+	dir, repo := newLocalRepository(t, "first commit", "second commit")
+	head, err := repo.Head()
+	assert.Nil(t, err)
+	headCommit, err := repo.CommitObject(head.Hash())
+	assert.Nil(t, err)
+
+	r := &ReleaserReconciler{GitCacheDir: t.TempDir()}
+	plan := r.plan(&devopsv1alpha1.Releaser{
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Version: "v0.0.1",
+			Repositories: []devopsv1alpha1.Repository{{
+				Address:      dir,
+				Branch:       "master",
+				Version:      "v0.0.1",
+				VersionFiles: []devopsv1alpha1.VersionFile{{Path: "README.md", Pattern: ".+"}},
+			}, {
+				Address:      dir,
+				Branch:       "master",
+				Version:      "v0.0.1",
+				VersionFiles: []devopsv1alpha1.VersionFile{{Path: "VERSION", Pattern: ".+"}},
+			}, {
+				Address:      dir,
+				Branch:       "master",
+				Commit:       headCommit.ParentHashes[0].String(),
+				Version:      "v0.0.1",
+				VersionFiles: []devopsv1alpha1.VersionFile{{Path: "README.md", Pattern: ".+"}},
+			}},
+		},
+	}, nil)
+
+	assert.Equal(t, 3, len(plan.Repositories))
+	assert.Empty(t, plan.Repositories[0].Error)
+	assert.Equal(t, []string{"README.md"}, plan.Repositories[0].VersionFiles)
+	assert.Contains(t, plan.Repositories[1].Error, "failed to update version file VERSION")
+	assert.Contains(t, plan.Repositories[2].Error, "not the head of branch master")
+
+	// nothing was written
+	cacheDir, err := getRepoCacheDir(r.GitCacheDir, dir)
+	assert.Nil(t, err)
+	data, err := ioutil.ReadFile(path.Join(cacheDir, "README.md"))
+	assert.Nil(t, err)
+	assert.Equal(t, "1", string(data))
+}
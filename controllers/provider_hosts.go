@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// LoadProviderHosts loads the hostnames of the self-hosted git providers from a ConfigMap whose keys are the
+// hostnames and values are the providers, such as: gitlab.example.com: gitlab. It is fine that the ConfigMap is missing.
+func LoadProviderHosts(ctx context.Context, reader client.Reader, key types.NamespacedName) (err error) {
+	configMap := &v1.ConfigMap{}
+	if err = reader.Get(ctx, key, configMap); err != nil {
+		if apierrors.IsNotFound(err) {
+			err = nil
+		} else {
+			err = fmt.Errorf("failed to get ConfigMap %s, error: %v", key.String(), err)
+		}
+		return
+	}
+
+	hosts := map[string]devopsv1alpha1.Provider{}
+	for host, provider := range configMap.Data {
+		hosts[host] = devopsv1alpha1.Provider(strings.TrimSpace(provider))
+	}
+	if err = devopsv1alpha1.SetProviderHosts(hosts); err != nil {
+		err = fmt.Errorf("invalid ConfigMap %s, error: %v", key.String(), err)
+	}
+	return
+}
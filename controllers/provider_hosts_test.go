@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"context"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"testing"
+)
+
+func TestLoadProviderHosts(t *testing.T) {
+	defer func() {
+		_ = devopsv1alpha1.SetProviderHosts(nil)
+	}()
+	key := types.NamespacedName{Namespace: "ks-releaser-system", Name: "provider-hosts"}
+
+	// the ConfigMap is optional
+	err := LoadProviderHosts(context.TODO(), fake.NewFakeClientWithScheme(clientgoscheme.Scheme), key)
+	assert.Nil(t, err)
+
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+		Data:       map[string]string{"gitlab.example.com": "gitlab"},
+	}
+	err = LoadProviderHosts(context.TODO(), fake.NewFakeClientWithScheme(clientgoscheme.Scheme, configMap), key)
+	assert.Nil(t, err)
+	assert.Equal(t, devopsv1alpha1.ProviderGitlab, devopsv1alpha1.GetDefaultProvider(&devopsv1alpha1.Repository{
+		Address: "https://gitlab.example.com/x/b"}))
+
+	configMap.Data["git.example.com"] = "fake"
+	err = LoadProviderHosts(context.TODO(), fake.NewFakeClientWithScheme(clientgoscheme.Scheme, configMap), key)
+	assert.NotNil(t, err)
+}
@@ -24,7 +24,6 @@ import (
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"net/url"
 	"path"
 	"sigs.k8s.io/yaml"
 	"time"
@@ -36,11 +35,28 @@ import (
 	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
 )
 
+// pullRequestCheckInterval is the interval of checking the statuses of the GitOps pull request
+const pullRequestCheckInterval = time.Minute
+
+const (
+	// releaseRetryInterval is the backoff of retrying a failed release the first time, it doubles on each attempt
+	releaseRetryInterval = 5 * time.Second
+	// maxReleaseRetryInterval is the upper limit of the backoff of retrying a failed release
+	maxReleaseRetryInterval = 30 * time.Minute
+)
+
+// specHashAnnotation is the hash of the spec which was tried to release last time
+const specHashAnnotation = "releaser.devops.kubesphere.io/hash"
+
 // ReleaserReconciler reconciles a Releaser object
 type ReleaserReconciler struct {
 	logger logr.Logger
 	client.Client
 	GitCacheDir string
+	// MaxConcurrency is the upper limit of the repositories which can be released at the same time
+	MaxConcurrency int
+	// Clock returns the current date of the calendar versioning, time.Now is the default one
+	Clock func() time.Time
 
 	gitUser string
 }
@@ -49,6 +65,7 @@ type ReleaserReconciler struct {
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=releasers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=devops.kubesphere.io,resources=releasers/finalizers,verbs=update
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;watch;list
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;watch;list
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -67,15 +84,38 @@ func (r *ReleaserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 		return
 	}
 	spec := releaser.Spec
-	if spec.Phase != devopsv1alpha1.PhaseReady {
+	if needToInferVersions(releaser) {
+		var changed bool
+		if changed, err = r.inferVersions(ctx, releaser); err == nil && changed {
+			r.logger.Info("update the inferred versions", "name", releaser.Name, "version", releaser.Spec.Version)
+			err = r.Update(ctx, releaser)
+		}
+		if err != nil || changed {
+			return
+		}
+		// check the new commits periodically
+		result = ctrl.Result{RequeueAfter: inferVersionsInterval}
+	}
+
+	if needToMergePullRequest(releaser) {
+		result, err = r.mergePullRequest(ctx, releaser)
 		return
 	}
 
-	if !r.needToUpdate(ctx, releaser) {
+	if spec.DryRun {
+		// the plan is helpful before the phase is ready
+		if spec.Phase == devopsv1alpha1.PhaseDone || !needToPlan(releaser) {
+			return
+		}
+	} else if spec.Phase != devopsv1alpha1.PhaseReady {
+		return
+	} else if update, retryAfter := needToUpdate(releaser, time.Now()); !update {
+		if retryAfter > 0 && (result.RequeueAfter == 0 || retryAfter < result.RequeueAfter) {
+			result.RequeueAfter = retryAfter
+		}
 		return
 	}
 
-	r.logger.Info("start to release", "name", releaser.Name)
 	secret := &v1.Secret{}
 	if err = r.Get(ctx, types.NamespacedName{
 		Namespace: spec.Secret.Namespace,
@@ -84,13 +124,140 @@ func (r *ReleaserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 		return
 	}
 
+	if spec.DryRun {
+		r.logger.Info("start to plan the release", "name", releaser.Name)
+		releaser.Status.Plan = r.plan(releaser, secret)
+		err = r.Status().Update(ctx, releaser)
+		return
+	}
+
+	r.logger.Info("start to release", "name", releaser.Name)
+
 	releaser.Status.Conditions = make([]devopsv1alpha1.Condition, 0)
+	if releaser.Status.StartTime == nil {
+		releaser.Status.StartTime = &metav1.Time{Time: time.Now()}
+	}
+	if releaser.Annotations[specHashAnnotation] != ComputeHash(releaser.Spec) {
+		// count the attempts of the new spec from scratch
+		releaser.Status.Attempts = 0
+		releaser.Status.CompletionTime = nil
+	}
+	releaser.Status.Attempts++
+	releaser.Status.LastAttemptTime = &metav1.Time{Time: time.Now()}
 	r.gitUser = string(secret.Data[v1.BasicAuthUsernameKey])
 
-	var errSlice = ErrorSlice{}
-	for i, _ := range spec.Repositories {
-		repo := spec.Repositories[i]
-		releaseRrr := release(repo, secret, r.gitUser)
+	var errSlice ErrorSlice
+	option, err := r.getReleaseOption(releaser, secret)
+	if err != nil {
+		errSlice = errSlice.append(err)
+		addCondition(releaser, devopsv1alpha1.Condition{
+			ConditionType: devopsv1alpha1.ConditionTypeOther,
+			Status:        devopsv1alpha1.ConditionStatusFailed,
+			Message:       fmt.Sprintf("failed to prepare the release: %v", err),
+		})
+	} else {
+		errSlice = r.releaseStages(releaser, option)
+	}
+
+	if err = errSlice.ToError(); err == nil {
+		if err = r.markAsDone(secret, releaser, option.identity); err != nil {
+			condition := devopsv1alpha1.Condition{
+				ConditionType: devopsv1alpha1.ConditionTypeOther,
+				Status:        devopsv1alpha1.ConditionStatusFailed,
+				Message:       fmt.Sprintf("failed to mark as done: %v", err),
+			}
+			addCondition(releaser, condition)
+		}
+	}
+
+	if err == nil {
+		releaser.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+		metricsRecord(releaser.DeepCopy())
+	}
+
+	// the hash is recorded even if the release failed, so that it is retried with a backoff instead of at once
+	attempts := releaser.Status.Attempts
+	updateErr := r.Status().Update(ctx, releaser)
+	if updateErr == nil {
+		updateErr = r.updateHash(ctx, releaser)
+	}
+	if err == nil {
+		err = updateErr
+	}
+
+	if err != nil {
+		result = ctrl.Result{
+			RequeueAfter: getReleaseRetryBackoff(attempts),
+		}
+	}
+	return
+}
+
+// releaseStages releases the repositories stage by stage, stops the downstream stages once a stage failed
+func (r *ReleaserReconciler) releaseStages(releaser *devopsv1alpha1.Releaser, option releaseOption) (errSlice ErrorSlice) {
+	stages, err := releaser.Spec.GetReleaseStages()
+	if err != nil {
+		errSlice = errSlice.append(err)
+		addCondition(releaser, devopsv1alpha1.Condition{
+			ConditionType: devopsv1alpha1.ConditionTypeOther,
+			Status:        devopsv1alpha1.ConditionStatusFailed,
+			Message:       fmt.Sprintf("failed to find out the release stages: %v", err),
+		})
+		return
+	}
+
+	for i, stage := range stages {
+		repos := make([]devopsv1alpha1.Repository, len(stage))
+		for j, index := range stage {
+			repos[j] = releaser.Spec.Repositories[index]
+		}
+
+		if len(errSlice) > 0 {
+			for _, repo := range repos {
+				addCondition(releaser, devopsv1alpha1.Condition{
+					ConditionType: devopsv1alpha1.ConditionTypeRelease,
+					Status:        devopsv1alpha1.ConditionStatusFailed,
+					Message:       fmt.Sprintf("skipped %s because the upstream stage failed", repo.Address),
+				})
+			}
+			continue
+		}
+
+		r.logger.Info("start to release stage", "stage", i, "count", len(repos))
+		errSlice = append(errSlice, r.releaseRepositories(releaser, option, repos)...)
+	}
+	return
+}
+
+// releaseRepositories releases the repositories concurrently, the results will be recorded into the status
+func (r *ReleaserReconciler) releaseRepositories(releaser *devopsv1alpha1.Releaser, option releaseOption,
+	repos []devopsv1alpha1.Repository) (errSlice ErrorSlice) {
+	repoStatuses := make([]*devopsv1alpha1.RepositoryStatus, len(repos))
+	releaseErrs := make([]error, len(repos))
+	for i, _ := range repos {
+		repo := repos[i]
+		if isRepositoryReleased(releaser, repo, r.getClock()) {
+			r.logger.Info("skip the released repository", "address", repo.Address)
+			continue
+		}
+		repoStatuses[i] = newRepositoryStatus(releaser, repo, r.getClock())
+	}
+
+	runConcurrently(len(repos), r.getConcurrency(releaser), func(index int) {
+		repoStatus := repoStatuses[index]
+		if repoStatus == nil {
+			return
+		}
+
+		releaseErrs[index] = release(repos[index], option, repoStatus)
+		repoStatus.CompletionTime = &metav1.Time{Time: time.Now()}
+	})
+
+	// record the results in the same order with the repositories
+	for i, _ := range repos {
+		repo := repos[i]
+		repoStatus := repoStatuses[i]
+		releaseRrr := releaseErrs[i]
 
 		var condition devopsv1alpha1.Condition
 		if releaseRrr == nil {
@@ -108,46 +275,145 @@ func (r *ReleaserReconciler) Reconcile(ctx context.Context, req ctrl.Request) (r
 			}
 		}
 		addCondition(releaser, condition)
-	}
 
-	if err = errSlice.ToError(); err == nil {
-		if err = r.markAsDone(secret, releaser); err != nil {
-			condition := devopsv1alpha1.Condition{
-				ConditionType: devopsv1alpha1.ConditionTypeOther,
-				Status:        devopsv1alpha1.ConditionStatusFailed,
-				Message:       fmt.Sprintf("failed to mark as done: %v", err),
-			}
-			addCondition(releaser, condition)
+		// it was released by a previous attempt
+		if repoStatus == nil {
+			continue
+		}
+		if releaseRrr == nil {
+			repoStatus.Status = devopsv1alpha1.ConditionStatusSuccess
+		} else {
+			repoStatus.Status = devopsv1alpha1.ConditionStatusFailed
+			repoStatus.LastError = releaseRrr.Error()
 		}
+		releaser.Status.SetRepositoryStatus(*repoStatus)
 	}
+	return
+}
 
-	if err == nil {
-		releaser.Status.CompletionTime = &metav1.Time{Time: time.Now()}
-		metricsRecord(releaser.DeepCopy())
+// newReleaseOption creates the shared options of releasing the repositories
+func (r *ReleaserReconciler) newReleaseOption(releaser *devopsv1alpha1.Releaser, secret *v1.Secret) (option releaseOption) {
+	option = releaseOption{
+		secret:       secret,
+		identity:     newGitIdentity(r.gitUser),
+		cacheDir:     r.GitCacheDir,
+		createBranch: releaser.Spec.CreateBranch,
+		scheme:       getVersioningScheme(releaser, r.getClock()),
+	}
+	if releaser.Spec.ReleaseNotes != nil {
+		option.changelogFile = releaser.Spec.ReleaseNotes.ChangelogFile
 	}
+	return
+}
 
-	if updateErr := r.Status().Update(ctx, releaser); err == nil && updateErr == nil {
-		err = r.updateHash(ctx, releaser)
-	} else if err == nil && updateErr != nil {
-		err = updateErr
+// getReleaseOption creates the options of releasing the repositories, including the release notes template and
+// the author identity. The priority of the identity is: spec.author, the git secret, the signing secret.
+func (r *ReleaserReconciler) getReleaseOption(releaser *devopsv1alpha1.Releaser, secret *v1.Secret) (option releaseOption, err error) {
+	option = r.newReleaseOption(releaser, secret)
+	if option.releaseNotesTemplate, err = r.getReleaseNotesTemplate(releaser); err != nil {
+		err = fmt.Errorf("failed to find the release notes template: %v", err)
+		return
 	}
 
-	if err != nil {
-		result = ctrl.Result{
-			RequeueAfter: time.Second * 5,
+	if signing := releaser.Spec.Signing; signing != nil {
+		signingSecret := &v1.Secret{}
+		namespacedName := types.NamespacedName{Namespace: signing.Secret.Namespace, Name: signing.Secret.Name}
+		if namespacedName.Namespace == "" {
+			namespacedName.Namespace = releaser.Namespace
+		}
+		if err = r.Get(context.TODO(), namespacedName, signingSecret); err != nil {
+			err = fmt.Errorf("failed to find the signing secret from %v, error: %v", namespacedName, err)
+			return
 		}
+		if option.identity, err = option.identity.withSigningSecret(signingSecret); err != nil {
+			return
+		}
+	}
+
+	option.identity = option.identity.withSecret(secret)
+	if author := releaser.Spec.Author; author != nil {
+		option.identity = option.identity.withAuthor(author.Name, author.Email)
 	}
 	return
 }
 
-func (r *ReleaserReconciler) needToUpdate(ctx context.Context, releaser *devopsv1alpha1.Releaser) bool {
-	hash := releaser.Annotations["releaser.devops.kubesphere.io/hash"]
-	newHash := ComputeHash(releaser.Spec)
-	if hash == "" {
-		_ = r.updateHash(ctx, releaser)
-		return true
+// getReleaseNotesTemplate returns the inline release notes template, or the one from the referenced ConfigMap
+func (r *ReleaserReconciler) getReleaseNotesTemplate(releaser *devopsv1alpha1.Releaser) (tpl string, err error) {
+	releaseNotes := releaser.Spec.ReleaseNotes
+	if releaseNotes == nil {
+		return
+	}
+	if releaseNotes.Template != "" || releaseNotes.TemplateRef == nil {
+		tpl = releaseNotes.Template
+		return
+	}
+
+	ref := releaseNotes.TemplateRef
+	configMap := &v1.ConfigMap{}
+	namespacedName := types.NamespacedName{Namespace: releaser.Namespace, Name: ref.Name}
+	if err = r.Get(context.TODO(), namespacedName, configMap); err != nil {
+		err = fmt.Errorf("failed to find configmap from %v, error: %v", namespacedName, err)
+		return
+	}
+
+	var ok bool
+	if tpl, ok = configMap.Data[ref.Key]; !ok {
+		err = fmt.Errorf("cannot find key %s from configmap %v", ref.Key, namespacedName)
+	}
+	return
+}
+
+// getClock returns the clock of the calendar versioning
+func (r *ReleaserReconciler) getClock() func() time.Time {
+	if r.Clock == nil {
+		return time.Now
 	}
-	return hash != newHash
+	return r.Clock
+}
+
+// getConcurrency returns the number of repositories which can be released at the same time
+func (r *ReleaserReconciler) getConcurrency(releaser *devopsv1alpha1.Releaser) (concurrency int) {
+	concurrency = releaser.Spec.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	if r.MaxConcurrency > 0 && concurrency > r.MaxConcurrency {
+		concurrency = r.MaxConcurrency
+	}
+	return
+}
+
+// needToUpdate checks if the Releaser needs to be released. A new spec is released at once, but the failed release
+// of the same spec is retried once the backoff since the last attempt is over, retryAfter is the rest of the backoff.
+func needToUpdate(releaser *devopsv1alpha1.Releaser, now time.Time) (update bool, retryAfter time.Duration) {
+	if releaser.Annotations[specHashAnnotation] != ComputeHash(releaser.Spec) {
+		update = true
+		return
+	} else if releaser.Status.CompletionTime != nil {
+		return
+	}
+
+	// resume the release which was not completed
+	retryAfter = getReleaseRetryBackoff(releaser.Status.Attempts)
+	if lastAttempt := releaser.Status.LastAttemptTime; lastAttempt != nil {
+		retryAfter -= now.Sub(lastAttempt.Time)
+	}
+	if retryAfter <= 0 {
+		update, retryAfter = true, 0
+	}
+	return
+}
+
+// getReleaseRetryBackoff returns the backoff of retrying a release after the given attempts
+func getReleaseRetryBackoff(attempts int) (backoff time.Duration) {
+	backoff = releaseRetryInterval
+	for i := 1; i < attempts && backoff < maxReleaseRetryInterval; i++ {
+		backoff *= 2
+	}
+	if backoff > maxReleaseRetryInterval {
+		backoff = maxReleaseRetryInterval
+	}
+	return
 }
 
 func (r *ReleaserReconciler) updateHash(ctx context.Context, releaser *devopsv1alpha1.Releaser) (err error) {
@@ -155,7 +421,7 @@ func (r *ReleaserReconciler) updateHash(ctx context.Context, releaser *devopsv1a
 	if releaser.Annotations == nil {
 		releaser.Annotations = make(map[string]string)
 	}
-	releaser.Annotations["releaser.devops.kubesphere.io/hash"] = newHash
+	releaser.Annotations[specHashAnnotation] = newHash
 	err = r.Update(ctx, releaser)
 	return
 }
@@ -170,9 +436,13 @@ func (r *ReleaserReconciler) SetupWithManager(mgr ctrl.Manager) error {
 func (r *ReleaserReconciler) bumpResource(releaser *devopsv1alpha1.Releaser) (err error) {
 	ctx := context.Background()
 	releaser.Spec.Phase = devopsv1alpha1.PhaseDone
-	if err = r.Update(context.TODO(), releaser); err == nil {
+	if err = r.Update(context.TODO(), releaser); err == nil && hasNextReleaser(releaser) {
 		nextReleaser := releaser.DeepCopy()
-		isPre := bumpReleaser(nextReleaser, true)
+		var isPre bool
+		if isPre, err = bumpReleaser(nextReleaser, true, r.getClock()); err != nil {
+			err = fmt.Errorf("failed to bump releaser: %s, error: %v", releaser.GetName(), err)
+			return
+		}
 
 		if err = r.Create(ctx, nextReleaser); err != nil {
 			err = fmt.Errorf("failed to create next releaser: %s, error: %v", nextReleaser.GetName(), err)
@@ -180,7 +450,10 @@ func (r *ReleaserReconciler) bumpResource(releaser *devopsv1alpha1.Releaser) (er
 
 		if err == nil && isPre {
 			nextReleaserWithoutPre := releaser.DeepCopy()
-			bumpReleaser(nextReleaserWithoutPre, false)
+			if _, err = bumpReleaser(nextReleaserWithoutPre, false, r.getClock()); err != nil {
+				err = fmt.Errorf("failed to bump releaser: %s, error: %v", releaser.GetName(), err)
+				return
+			}
 
 			err = r.Get(ctx, types.NamespacedName{
 				Namespace: nextReleaserWithoutPre.Namespace,
@@ -198,7 +471,8 @@ func (r *ReleaserReconciler) bumpResource(releaser *devopsv1alpha1.Releaser) (er
 	return
 }
 
-func (r *ReleaserReconciler) markAsDone(secret *v1.Secret, releaser *devopsv1alpha1.Releaser) (err error) {
+func (r *ReleaserReconciler) markAsDone(secret *v1.Secret, releaser *devopsv1alpha1.Releaser,
+	identity gitIdentity) (err error) {
 	gitOps := releaser.Spec.GitOps
 	if gitOps == nil || !gitOps.Enable {
 		err = r.bumpResource(releaser)
@@ -215,23 +489,48 @@ func (r *ReleaserReconciler) markAsDone(secret *v1.Secret, releaser *devopsv1alp
 			err = fmt.Errorf("failed to find secret from %v, error: %v", namespacedName, err)
 			return
 		}
+		identity = identity.withSecret(secret)
+		if author := releaser.Spec.Author; author != nil {
+			identity = identity.withAuthor(author.Name, author.Email)
+		}
 	}
 
 	var gitRepo *git.Repository
 	repo := gitOps.Repository
+	identity = identity.forRepository(repo)
 	if gitRepo, err = clone(repo.Address, repo.Branch, getAuth(secret), r.GitCacheDir); err != nil {
 		err = fmt.Errorf("failed to clone repository: %s, error: %v", repo.Address, err)
 		return
 	}
 
-	var gitRepoURL *url.URL
-	if gitRepoURL, err = url.Parse(repo.Address); err != nil {
-		return
+	// commit the changes into a generated branch in the pr mode, then push them at once
+	pullRequestMode := gitOps.Mode == devopsv1alpha1.GitOpsModePullRequest
+	branch := getPullRequestBranchName(releaser)
+	if pullRequestMode {
+		if err = checkoutNewBranch(gitRepo, branch); err != nil {
+			err = fmt.Errorf("failed to checkout branch %s, error: %v", branch, err)
+			return
+		}
+	}
+	save := func(targetFile string, data []byte, commitMessage string) error {
+		if pullRequestMode {
+			return saveAndCommit(gitRepo, identity, targetFile, data, commitMessage)
+		}
+		return saveAndPush(gitRepo, identity, targetFile, data, secret, commitMessage)
 	}
 
-	repoDir := path.Join(r.GitCacheDir, gitRepoURL.Path)
+	var repoDir string
+	if repoDir, err = getRepoCacheDir(r.GitCacheDir, repo.Address); err != nil {
+		return
+	}
 	currentReleaserPath := findReleaserFile(fmt.Sprintf("%s.yaml", releaser.Name), repoDir)
 
+	// the updated files will be committed together with the Releaser
+	if err = updateGitOpsFiles(repoDir, releaser); err != nil {
+		err = fmt.Errorf("failed to update the files of repository: %s, error: %v", repo.Address, err)
+		return
+	}
+
 	var data []byte
 	copiedReleaser := releaser.DeepCopy()
 	copiedReleaser.Spec.Phase = devopsv1alpha1.PhaseDone
@@ -241,8 +540,7 @@ func (r *ReleaserReconciler) markAsDone(secret *v1.Secret, releaser *devopsv1alp
 	data, _ = yaml.Marshal(copiedReleaser)
 
 	r.logger.Info("start to commit phase to be done", "name", releaser.Name)
-	if err = saveAndPush(gitRepo, r.gitUser, currentReleaserPath, data, secret,
-		fmt.Sprintf("release %s", releaser.Name)); err != nil {
+	if err = save(currentReleaserPath, data, fmt.Sprintf("release %s", releaser.Name)); err != nil {
 		err = fmt.Errorf("failed to write file %s, error: %v", currentReleaserPath, err)
 		return
 	}
@@ -250,27 +548,107 @@ func (r *ReleaserReconciler) markAsDone(secret *v1.Secret, releaser *devopsv1alp
 	r.logger.Info("start to create next release file")
 	var bumpFilename string
 	var isPre bool
-	if data, bumpFilename, isPre, err = bumpReleaserAsData(data, true); err != nil {
+	if data, bumpFilename, isPre, err = bumpReleaserAsData(data, true, r.getClock()); err != nil {
 		err = fmt.Errorf("failed to bump releaser: %s, error: %v", currentReleaserPath, err)
-	} else {
+	} else if bumpFilename != "" {
 		bumpFilePath := path.Join(path.Dir(currentReleaserPath), bumpFilename)
-		err = saveAndPush(gitRepo, r.gitUser, bumpFilePath, data, secret,
-			fmt.Sprintf("prepare the next release of %s", releaser.Name))
+		err = save(bumpFilePath, data, fmt.Sprintf("prepare the next release of %s", releaser.Name))
 	}
 
 	// try to prepare the next version which is not a preRlease
 	if err == nil && isPre {
-		if data, bumpFilename, _, err = bumpReleaserAsData(data, false); err != nil {
+		if data, bumpFilename, _, err = bumpReleaserAsData(data, false, r.getClock()); err != nil {
 			err = fmt.Errorf("failed to bump releaser: %s, error: %v", currentReleaserPath, err)
 		} else {
 			bumpFilePath := path.Join(path.Dir(currentReleaserPath), bumpFilename)
 			if ok, _ := PathExists(bumpFilePath); !ok {
-				err = saveAndPush(gitRepo, r.gitUser, bumpFilePath, data, secret,
-					fmt.Sprintf("prepare the next release of %s", releaser.Name))
+				err = save(bumpFilePath, data, fmt.Sprintf("prepare the next release of %s", releaser.Name))
 			}
 		}
 
 	}
+
+	if err == nil && pullRequestMode {
+		err = r.openPullRequest(releaser, gitRepo, secret, branch)
+	}
+	return
+}
+
+// getPullRequestBranchName returns the branch name of the GitOps pull request, such as: ks-releaser/v1.0.0
+func getPullRequestBranchName(releaser *devopsv1alpha1.Releaser) string {
+	return fmt.Sprintf("ks-releaser/%s", releaser.Name)
+}
+
+// openPullRequest pushes the branch, then opens a pull request to the GitOps repository
+func (r *ReleaserReconciler) openPullRequest(releaser *devopsv1alpha1.Releaser, gitRepo *git.Repository,
+	secret *v1.Secret, branch string) (err error) {
+	repo := releaser.Spec.GitOps.Repository
+	if err = forcePushBranch(gitRepo, branch, getAuth(secret)); err != nil {
+		err = fmt.Errorf("failed to push branch %s, error: %v", branch, err)
+		return
+	}
+
+	gitProvider := getGitProviderClient(repo, secret)
+	if gitProvider == nil {
+		err = fmt.Errorf("git provider of %s does not support pull requests", repo.Address)
+		return
+	}
+
+	r.logger.Info("open a pull request", "name", releaser.Name, "branch", branch)
+	var number int
+	var link string
+	if number, link, err = gitProvider.CreatePullRequest(fmt.Sprintf("release %s", releaser.Name),
+		fmt.Sprintf("Mark %s as done, and prepare the next release. It was opened by ks-releaser.", releaser.Name),
+		branch, repo.Branch); err != nil {
+		err = fmt.Errorf("failed to open a pull request from %s to %s, error: %v", branch, repo.Branch, err)
+		return
+	}
+	releaser.Status.PullRequest = &devopsv1alpha1.PullRequestStatus{
+		Number: number,
+		Link:   link,
+		Branch: branch,
+	}
+	return
+}
+
+// needToMergePullRequest checks if the GitOps pull request is waiting for the auto merging
+func needToMergePullRequest(releaser *devopsv1alpha1.Releaser) bool {
+	gitOps := releaser.Spec.GitOps
+	pullRequest := releaser.Status.PullRequest
+	return gitOps != nil && gitOps.Enable && gitOps.Mode == devopsv1alpha1.GitOpsModePullRequest && gitOps.AutoMerge &&
+		pullRequest != nil && !pullRequest.Merged
+}
+
+// mergePullRequest merges the GitOps pull request once the checks passed, or checks it again later
+func (r *ReleaserReconciler) mergePullRequest(ctx context.Context, releaser *devopsv1alpha1.Releaser) (
+	result ctrl.Result, err error) {
+	secretRef := releaser.GetGitOpsSecret()
+	if secretRef.Namespace == "" {
+		secretRef.Namespace = releaser.Namespace
+	}
+	secret := &v1.Secret{}
+	if err = r.Get(ctx, types.NamespacedName{Namespace: secretRef.Namespace, Name: secretRef.Name}, secret); err != nil {
+		return
+	}
+
+	repo := releaser.Spec.GitOps.Repository
+	gitProvider := getGitProviderClient(repo, secret)
+	if gitProvider == nil {
+		err = fmt.Errorf("git provider of %s does not support pull requests", repo.Address)
+		return
+	}
+
+	var merged bool
+	pullRequest := releaser.Status.PullRequest
+	if merged, err = gitProvider.MergePullRequest(pullRequest.Number); err != nil {
+		err = fmt.Errorf("failed to merge pull request %d, error: %v", pullRequest.Number, err)
+	} else if !merged {
+		result = ctrl.Result{RequeueAfter: pullRequestCheckInterval}
+	} else {
+		r.logger.Info("the pull request was merged", "name", releaser.Name, "number", pullRequest.Number)
+		pullRequest.Merged = true
+		err = r.Status().Update(ctx, releaser)
+	}
 	return
 }
 
@@ -292,6 +670,37 @@ func metricsRecord(releaser *devopsv1alpha1.Releaser) {
 	}
 }
 
+// newRepositoryStatus creates the status for a new release attempt of a repository, CalVer takes the current date
+// from the clock
+func newRepositoryStatus(releaser *devopsv1alpha1.Releaser, repo devopsv1alpha1.Repository,
+	now func() time.Time) (status *devopsv1alpha1.RepositoryStatus) {
+	status = &devopsv1alpha1.RepositoryStatus{
+		Name:      repo.Name,
+		Address:   repo.Address,
+		Version:   repo.Version,
+		Action:    getAction(repo, getVersioningScheme(releaser, now)),
+		StartTime: &metav1.Time{Time: time.Now()},
+		Attempts:  1,
+	}
+
+	// keep counting the attempts of the same version, and resume from the previous progress
+	if previous := releaser.Status.GetRepositoryStatus(repo); previous != nil && previous.Version == repo.Version {
+		status.Attempts = previous.Attempts + 1
+		status.Commit = previous.Commit
+		status.ReleaseBranch = previous.ReleaseBranch
+		status.ReleaseURL = previous.ReleaseURL
+	}
+	return
+}
+
+// isRepositoryReleased checks if the repository was released completely by a previous attempt
+func isRepositoryReleased(releaser *devopsv1alpha1.Releaser, repo devopsv1alpha1.Repository, now func() time.Time) bool {
+	previous := releaser.Status.GetRepositoryStatus(repo)
+	return previous != nil && previous.Version == repo.Version &&
+		previous.Action == getAction(repo, getVersioningScheme(releaser, now)) &&
+		previous.Status == devopsv1alpha1.ConditionStatusSuccess
+}
+
 // addCondition adds or replaces a condition
 func addCondition(releaser *devopsv1alpha1.Releaser, condition devopsv1alpha1.Condition) {
 	releaser.Status.Conditions = append(releaser.Status.Conditions, condition)
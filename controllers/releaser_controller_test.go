@@ -3,14 +3,20 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-logr/logr"
 	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"path"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"testing"
+	"time"
 )
 
 func TestReleaserReconciler_bumpResource(t *testing.T) {
@@ -91,3 +97,393 @@ func TestReleaserReconciler_bumpResource(t *testing.T) {
 		})
 	}
 }
+
+func Test_newRepositoryStatus(t *testing.T) {
+	repo := devopsv1alpha1.Repository{
+		Name:    "test",
+		Address: "https://github.com/x/b",
+		Version: "v0.0.1-alpha.0",
+		Action:  devopsv1alpha1.ActionAuto,
+	}
+	releaser := &devopsv1alpha1.Releaser{}
+
+	status := newRepositoryStatus(releaser, repo, time.Now)
+	assert.Equal(t, 1, status.Attempts)
+	assert.Equal(t, devopsv1alpha1.ActionPreRelease, status.Action)
+	assert.NotNil(t, status.StartTime)
+
+	// keep counting the attempts of the same version, and resume from the pushed tag
+	status.Commit = "sha"
+	releaser.Status.SetRepositoryStatus(*status)
+	status = newRepositoryStatus(releaser, repo, time.Now)
+	assert.Equal(t, 2, status.Attempts)
+	assert.Equal(t, "sha", status.Commit)
+
+	// start over once the version was changed
+	releaser.Status.SetRepositoryStatus(*status)
+	repo.Version = "v0.0.1-alpha.1"
+	status = newRepositoryStatus(releaser, repo, time.Now)
+	assert.Equal(t, 1, status.Attempts)
+	assert.Empty(t, status.Commit)
+}
+
+func Test_isRepositoryReleased(t *testing.T) {
+	repo := devopsv1alpha1.Repository{
+		Name:    "test",
+		Address: "https://github.com/x/b",
+		Version: "v0.0.1",
+		Action:  devopsv1alpha1.ActionTag,
+	}
+
+	tests := []struct {
+		name   string
+		status []devopsv1alpha1.RepositoryStatus
+		want   bool
+	}{{
+		name: "without status",
+		want: false,
+	}, {
+		name: "released successfully",
+		status: []devopsv1alpha1.RepositoryStatus{{
+			Name: "test", Address: "https://github.com/x/b", Version: "v0.0.1",
+			Action: devopsv1alpha1.ActionTag, Status: devopsv1alpha1.ConditionStatusSuccess,
+		}},
+		want: true,
+	}, {
+		name: "failed to release",
+		status: []devopsv1alpha1.RepositoryStatus{{
+			Name: "test", Address: "https://github.com/x/b", Version: "v0.0.1",
+			Action: devopsv1alpha1.ActionTag, Status: devopsv1alpha1.ConditionStatusFailed,
+		}},
+		want: false,
+	}, {
+		name: "released with another version",
+		status: []devopsv1alpha1.RepositoryStatus{{
+			Name: "test", Address: "https://github.com/x/b", Version: "v0.0.0",
+			Action: devopsv1alpha1.ActionTag, Status: devopsv1alpha1.ConditionStatusSuccess,
+		}},
+		want: false,
+	}, {
+		name: "released with another action",
+		status: []devopsv1alpha1.RepositoryStatus{{
+			Name: "test", Address: "https://github.com/x/b", Version: "v0.0.1",
+			Action: devopsv1alpha1.ActionRelease, Status: devopsv1alpha1.ConditionStatusSuccess,
+		}},
+		want: false,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaser := &devopsv1alpha1.Releaser{
+				Status: devopsv1alpha1.ReleaserStatus{Repositories: tt.status},
+			}
+			assert.Equal(t, tt.want, isRepositoryReleased(releaser, repo, time.Now))
+		})
+	}
+}
+
+func TestReleaserReconciler_getConcurrency(t *testing.T) {
+	tests := []struct {
+		name           string
+		concurrency    int
+		maxConcurrency int
+		want           int
+	}{{
+		name: "default value",
+		want: 1,
+	}, {
+		name:        "without the upper limit",
+		concurrency: 20,
+		want:        20,
+	}, {
+		name:           "less than the upper limit",
+		concurrency:    3,
+		maxConcurrency: 5,
+		want:           3,
+	}, {
+		name:           "greater than the upper limit",
+		concurrency:    10,
+		maxConcurrency: 5,
+		want:           5,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &ReleaserReconciler{MaxConcurrency: tt.maxConcurrency}
+			releaser := &devopsv1alpha1.Releaser{
+				Spec: devopsv1alpha1.ReleaserSpec{Concurrency: tt.concurrency},
+			}
+			assert.Equal(t, tt.want, r.getConcurrency(releaser))
+		})
+	}
+}
+
+func TestReleaserReconciler_releaseStages(t *testing.T) {
+	dir, _ := newLocalRepository(t, "first commit")
+
+	r := &ReleaserReconciler{
+		logger:      logr.Discard(),
+		GitCacheDir: t.TempDir(),
+	}
+	releaser := &devopsv1alpha1.Releaser{
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Repositories: []devopsv1alpha1.Repository{{
+				Name:      "console",
+				Address:   dir,
+				Branch:    "master",
+				Version:   "v0.0.1",
+				DependsOn: []string{"api"},
+			}, {
+				Name:    "api",
+				Address: dir,
+				Branch:  "invalid",
+				Version: "v0.0.1",
+			}},
+		},
+	}
+
+	errSlice := r.releaseStages(releaser, r.newReleaseOption(releaser, &corev1.Secret{}))
+	assert.Equal(t, 1, len(errSlice))
+	assert.Equal(t, 2, len(releaser.Status.Conditions))
+	assert.Contains(t, releaser.Status.Conditions[0].Message, "failed to release")
+	assert.Contains(t, releaser.Status.Conditions[1].Message, "skipped")
+	// the downstream repository was not released
+	assert.Equal(t, 1, len(releaser.Status.Repositories))
+	assert.Equal(t, "api", releaser.Status.Repositories[0].Name)
+
+	// release the downstream one once the upstream is fixed
+	releaser.Status = devopsv1alpha1.ReleaserStatus{}
+	releaser.Spec.Repositories[1].Branch = "master"
+	errSlice = r.releaseStages(releaser, r.newReleaseOption(releaser, &corev1.Secret{}))
+	assert.Equal(t, 0, len(errSlice))
+	assert.Equal(t, 2, len(releaser.Status.Repositories))
+
+	// circular dependencies
+	releaser.Spec.Repositories[1].DependsOn = []string{"console"}
+	errSlice = r.releaseStages(releaser, r.newReleaseOption(releaser, &corev1.Secret{}))
+	assert.Equal(t, 1, len(errSlice))
+}
+
+func TestReleaserReconciler_getReleaseNotesTemplate(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{Namespace: "fake", Name: "templates"},
+		Data:       map[string]string{"console": "{{.Version}}"},
+	}
+	r := &ReleaserReconciler{
+		Client: fake.NewFakeClientWithScheme(clientgoscheme.Scheme, configMap),
+	}
+
+	tests := []struct {
+		name         string
+		releaseNotes *devopsv1alpha1.ReleaseNotes
+		want         string
+		wantErr      bool
+	}{{
+		name: "no release notes",
+	}, {
+		name:         "inline template",
+		releaseNotes: &devopsv1alpha1.ReleaseNotes{Template: "inline"},
+		want:         "inline",
+	}, {
+		name: "template from configmap",
+		releaseNotes: &devopsv1alpha1.ReleaseNotes{TemplateRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "templates"},
+			Key:                  "console",
+		}},
+		want: "{{.Version}}",
+	}, {
+		name: "missing key",
+		releaseNotes: &devopsv1alpha1.ReleaseNotes{TemplateRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "templates"},
+			Key:                  "fake",
+		}},
+		wantErr: true,
+	}, {
+		name: "missing configmap",
+		releaseNotes: &devopsv1alpha1.ReleaseNotes{TemplateRef: &corev1.ConfigMapKeySelector{
+			LocalObjectReference: corev1.LocalObjectReference{Name: "fake"},
+			Key:                  "console",
+		}},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaser := &devopsv1alpha1.Releaser{
+				ObjectMeta: v1.ObjectMeta{Namespace: "fake"},
+				Spec:       devopsv1alpha1.ReleaserSpec{ReleaseNotes: tt.releaseNotes},
+			}
+			tpl, err := r.getReleaseNotesTemplate(releaser)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, tpl)
+		})
+	}
+}
+
+func TestReleaserReconciler_getReleaseOption(t *testing.T) {
+	signingSecret := &corev1.Secret{
+		ObjectMeta: v1.ObjectMeta{Namespace: "fake", Name: "signing"},
+		Data:       map[string][]byte{signingKeySecretKey: newArmoredSigningKey(t, "bot", "bot@example.com", nil)},
+	}
+	r := &ReleaserReconciler{
+		Client:  fake.NewFakeClientWithScheme(clientgoscheme.Scheme, signingSecret),
+		gitUser: "linuxsuren",
+	}
+
+	tests := []struct {
+		name      string
+		spec      devopsv1alpha1.ReleaserSpec
+		secret    map[string][]byte
+		wantName  string
+		wantEmail string
+		wantSign  bool
+		wantErr   bool
+	}{{
+		name:     "from the username",
+		wantName: "linuxsuren",
+	}, {
+		name:      "from the secret",
+		secret:    map[string][]byte{nameSecretKey: []byte("bot"), emailSecretKey: []byte("bot@gitea.com")},
+		wantName:  "bot",
+		wantEmail: "bot@gitea.com",
+	}, {
+		name:      "spec.author has the highest priority",
+		spec:      devopsv1alpha1.ReleaserSpec{Author: &devopsv1alpha1.Author{Email: "author@gitea.com"}},
+		secret:    map[string][]byte{nameSecretKey: []byte("bot"), emailSecretKey: []byte("bot@gitea.com")},
+		wantName:  "bot",
+		wantEmail: "author@gitea.com",
+	}, {
+		name: "from the signing key",
+		spec: devopsv1alpha1.ReleaserSpec{Signing: &devopsv1alpha1.Signing{
+			Secret: corev1.SecretReference{Name: "signing"},
+		}},
+		wantName:  "bot",
+		wantEmail: "bot@example.com",
+		wantSign:  true,
+	}, {
+		name: "signing secret not found",
+		spec: devopsv1alpha1.ReleaserSpec{Signing: &devopsv1alpha1.Signing{
+			Secret: corev1.SecretReference{Name: "signing", Namespace: "other"},
+		}},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaser := &devopsv1alpha1.Releaser{ObjectMeta: v1.ObjectMeta{Namespace: "fake"}, Spec: tt.spec}
+			option, err := r.getReleaseOption(releaser, &corev1.Secret{Data: tt.secret})
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			if tt.wantErr {
+				return
+			}
+			assert.Equal(t, tt.wantName, option.identity.name)
+			assert.Equal(t, tt.wantEmail, option.identity.email)
+			assert.Equal(t, tt.wantSign, option.identity.signKey != nil)
+		})
+	}
+}
+
+func TestReleaserReconciler_markAsDoneWithPullRequest(t *testing.T) {
+	dir, remote := newLocalRepository(t, "init")
+	err := ioutil.WriteFile(path.Join(dir, "test.yaml"), []byte("kind: Releaser"), 0644)
+	assert.Nil(t, err)
+	err = addAndCommit(remote, newGitIdentity("user"), "add releaser")
+	assert.Nil(t, err)
+	head, err := remote.Head()
+	assert.Nil(t, err)
+
+	r := &ReleaserReconciler{
+		GitCacheDir: t.TempDir(),
+		logger:      logr.Discard(),
+	}
+	releaser := &devopsv1alpha1.Releaser{
+		ObjectMeta: v1.ObjectMeta{Namespace: "fake", Name: "test"},
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Version: "v0.0.1",
+			GitOps: &devopsv1alpha1.GitOps{
+				Enable:     true,
+				Mode:       devopsv1alpha1.GitOpsModePullRequest,
+				Repository: devopsv1alpha1.Repository{Address: dir, Branch: "master"},
+			},
+			NextVersion: &devopsv1alpha1.NextVersion{Strategy: devopsv1alpha1.BumpStrategyNone},
+		},
+	}
+
+	// there is no pull request support for a local repository
+	err = r.markAsDone(&corev1.Secret{}, releaser, newGitIdentity("user"))
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "does not support pull requests")
+	assert.Nil(t, releaser.Status.PullRequest)
+
+	// the changes were pushed into the generated branch instead of the base one
+	branch, err := remote.Reference(plumbing.NewBranchReferenceName("ks-releaser/test"), true)
+	assert.Nil(t, err)
+	commit, err := remote.CommitObject(branch.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, "release test", commit.Message)
+	master, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, head.Hash(), master.Hash())
+}
+
+func Test_needToMergePullRequest(t *testing.T) {
+	newReleaser := func(autoMerge bool, pullRequest *devopsv1alpha1.PullRequestStatus) *devopsv1alpha1.Releaser {
+		return &devopsv1alpha1.Releaser{
+			Spec: devopsv1alpha1.ReleaserSpec{GitOps: &devopsv1alpha1.GitOps{
+				Enable: true, Mode: devopsv1alpha1.GitOpsModePullRequest, AutoMerge: autoMerge,
+			}},
+			Status: devopsv1alpha1.ReleaserStatus{PullRequest: pullRequest},
+		}
+	}
+	assert.False(t, needToMergePullRequest(&devopsv1alpha1.Releaser{}))
+	assert.False(t, needToMergePullRequest(newReleaser(true, nil)))
+	assert.False(t, needToMergePullRequest(newReleaser(false, &devopsv1alpha1.PullRequestStatus{Number: 1})))
+	assert.False(t, needToMergePullRequest(newReleaser(true, &devopsv1alpha1.PullRequestStatus{Number: 1, Merged: true})))
+	assert.True(t, needToMergePullRequest(newReleaser(true, &devopsv1alpha1.PullRequestStatus{Number: 1})))
+}
+
+func Test_needToUpdate(t *testing.T) {
+	now := time.Date(2021, time.September, 1, 0, 0, 0, 0, time.UTC)
+	newReleaser := func(attempts int, lastAttempt time.Duration, completed bool) *devopsv1alpha1.Releaser {
+		releaser := &devopsv1alpha1.Releaser{
+			Spec: devopsv1alpha1.ReleaserSpec{Version: "v0.0.1"},
+			Status: devopsv1alpha1.ReleaserStatus{
+				Attempts:        attempts,
+				LastAttemptTime: &v1.Time{Time: now.Add(-lastAttempt)},
+			},
+		}
+		releaser.Annotations = map[string]string{specHashAnnotation: ComputeHash(releaser.Spec)}
+		if completed {
+			releaser.Status.CompletionTime = &v1.Time{Time: now}
+		}
+		return releaser
+	}
+
+	// a new spec is released at once
+	update, retryAfter := needToUpdate(&devopsv1alpha1.Releaser{}, now)
+	assert.True(t, update)
+	assert.Zero(t, retryAfter)
+	changed := newReleaser(3, 0, false)
+	changed.Spec.Version = "v0.0.2"
+	update, _ = needToUpdate(changed, now)
+	assert.True(t, update)
+
+	// the completed release is not retried
+	update, retryAfter = needToUpdate(newReleaser(1, time.Hour, true), now)
+	assert.False(t, update)
+	assert.Zero(t, retryAfter)
+
+	// the failed release is retried after the backoff
+	update, retryAfter = needToUpdate(newReleaser(1, time.Second, false), now)
+	assert.False(t, update)
+	assert.Equal(t, 4*time.Second, retryAfter)
+	update, retryAfter = needToUpdate(newReleaser(3, 15*time.Second, false), now)
+	assert.False(t, update)
+	assert.Equal(t, 5*time.Second, retryAfter)
+	update, retryAfter = needToUpdate(newReleaser(3, 20*time.Second, false), now)
+	assert.True(t, update)
+	assert.Zero(t, retryAfter)
+}
+
+func Test_getReleaseRetryBackoff(t *testing.T) {
+	assert.Equal(t, releaseRetryInterval, getReleaseRetryBackoff(0))
+	assert.Equal(t, releaseRetryInterval, getReleaseRetryBackoff(1))
+	assert.Equal(t, 4*releaseRetryInterval, getReleaseRetryBackoff(3))
+	assert.Equal(t, maxReleaseRetryInterval, getReleaseRetryBackoff(100))
+}
@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// signingKeySecretKey is the key of the armored OpenPGP private key in the signing secret
+	signingKeySecretKey = "signing-key"
+	// passphraseSecretKey is the key of the passphrase of the private key, it is optional
+	passphraseSecretKey = "passphrase"
+)
+
+// withSigningSecret loads the signing key from the secret. The identity comes from the name and email keys of
+// the secret, or the primary identity of the signing key.
+func (i gitIdentity) withSigningSecret(secret *v1.Secret) (identity gitIdentity, err error) {
+	identity = i
+	if identity.signKey, err = loadSigningKey(secret); err != nil {
+		return
+	}
+
+	if primary := identity.signKey.PrimaryIdentity(); primary != nil && primary.UserId != nil {
+		identity = identity.withAuthor(primary.UserId.Name, primary.UserId.Email)
+	}
+	identity = identity.withSecret(secret)
+	return
+}
+
+// loadSigningKey reads the armored OpenPGP private key from the secret, decrypts it if the passphrase exists
+func loadSigningKey(secret *v1.Secret) (entity *openpgp.Entity, err error) {
+	data := secret.Data[signingKeySecretKey]
+	if len(data) == 0 {
+		err = fmt.Errorf("key %s is required in the signing secret", signingKeySecretKey)
+		return
+	}
+
+	var entities openpgp.EntityList
+	if entities, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data)); err != nil {
+		err = fmt.Errorf("cannot read the armored signing key, error: %v", err)
+		return
+	}
+	if len(entities) == 0 || entities[0].PrivateKey == nil {
+		err = fmt.Errorf("cannot find the private key from the signing secret")
+		return
+	}
+	entity = entities[0]
+
+	passphrase := secret.Data[passphraseSecretKey]
+	if entity.PrivateKey.Encrypted {
+		if err = entity.PrivateKey.Decrypt(passphrase); err != nil {
+			err = fmt.Errorf("cannot decrypt the signing key, error: %v", err)
+			return
+		}
+	}
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err = subkey.PrivateKey.Decrypt(passphrase); err != nil {
+				err = fmt.Errorf("cannot decrypt the signing subkey, error: %v", err)
+				return
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,112 @@
+package controllers
+
+import (
+	"bytes"
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"testing"
+)
+
+func newArmoredSigningKey(t *testing.T, name, email string, passphrase []byte) []byte {
+	entity, err := openpgp.NewEntity(name, "", email, nil)
+	assert.Nil(t, err)
+	if passphrase != nil {
+		assert.Nil(t, entity.PrivateKey.Encrypt(passphrase))
+		for _, subkey := range entity.Subkeys {
+			assert.Nil(t, subkey.PrivateKey.Encrypt(passphrase))
+		}
+	}
+
+	buf := &bytes.Buffer{}
+	writer, err := armor.Encode(buf, openpgp.PrivateKeyType, nil)
+	assert.Nil(t, err)
+	assert.Nil(t, entity.SerializePrivateWithoutSigning(writer, nil))
+	assert.Nil(t, writer.Close())
+	return buf.Bytes()
+}
+
+func Test_gitIdentity_withSigningSecret(t *testing.T) {
+	key := newArmoredSigningKey(t, "bot", "bot@example.com", nil)
+	encryptedKey := newArmoredSigningKey(t, "bot", "bot@example.com", []byte("secret"))
+
+	tests := []struct {
+		name      string
+		data      map[string][]byte
+		wantName  string
+		wantEmail string
+		wantErr   bool
+	}{{
+		name:    "without the signing key",
+		data:    map[string][]byte{},
+		wantErr: true,
+	}, {
+		name:    "invalid signing key",
+		data:    map[string][]byte{signingKeySecretKey: []byte("invalid")},
+		wantErr: true,
+	}, {
+		name:      "identity from the signing key",
+		data:      map[string][]byte{signingKeySecretKey: key},
+		wantName:  "bot",
+		wantEmail: "bot@example.com",
+	}, {
+		name: "identity from the secret",
+		data: map[string][]byte{
+			signingKeySecretKey: key,
+			nameSecretKey:       []byte("releaser"),
+			emailSecretKey:      []byte("releaser@example.com"),
+		},
+		wantName:  "releaser",
+		wantEmail: "releaser@example.com",
+	}, {
+		name:      "encrypted signing key",
+		data:      map[string][]byte{signingKeySecretKey: encryptedKey, passphraseSecretKey: []byte("secret")},
+		wantName:  "bot",
+		wantEmail: "bot@example.com",
+	}, {
+		name:    "wrong passphrase",
+		data:    map[string][]byte{signingKeySecretKey: encryptedKey, passphraseSecretKey: []byte("wrong")},
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := newGitIdentity("user").withSigningSecret(&v1.Secret{Data: tt.data})
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.NotNil(t, identity.signKey)
+			assert.Equal(t, tt.wantName, identity.name)
+			assert.Equal(t, tt.wantEmail, identity.email)
+		})
+	}
+}
+
+func Test_signedTagAndCommit(t *testing.T) {
+	_, repo := newLocalRepository(t, "feat: init")
+	identity, err := newGitIdentity("user").withSigningSecret(&v1.Secret{Data: map[string][]byte{
+		signingKeySecretKey: newArmoredSigningKey(t, "bot", "bot@example.com", nil),
+	}})
+	assert.Nil(t, err)
+
+	err = addAndCommit(repo, identity, "chore: signed commit")
+	assert.Nil(t, err)
+	head, err := repo.Head()
+	assert.Nil(t, err)
+	commit, err := repo.CommitObject(head.Hash())
+	assert.Nil(t, err)
+	assert.NotEmpty(t, commit.PGPSignature)
+	assert.Equal(t, "bot@example.com", commit.Author.Email)
+
+	created, err := setTag(repo, "v0.0.1", "v0.0.1", identity, head.Hash(), "")
+	assert.Nil(t, err)
+	assert.True(t, created)
+	ref, err := repo.Tag("v0.0.1")
+	assert.Nil(t, err)
+	tag, err := repo.TagObject(ref.Hash())
+	assert.Nil(t, err)
+	assert.NotEmpty(t, tag.PGPSignature)
+	assert.Equal(t, "bot", tag.Tagger.Name)
+}
@@ -22,6 +22,7 @@ import (
 	"fmt"
 	"github.com/go-logr/logr"
 	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/controllers/internal_scm"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -32,6 +33,9 @@ import (
 	"text/template"
 )
 
+// failureIssueLabel is the label of the issues which report the release failures
+const failureIssueLabel = "release-failure"
+
 // StatusController is responsible for reporting errors to git provider
 type StatusController struct {
 	logger logr.Logger
@@ -59,8 +63,14 @@ func (c *StatusController) Reconcile(ctx context.Context, req ctrl.Request) (res
 		return
 	}
 
-	// do not report the status when the phase was done
-	if releaser.Spec.Phase == devopsv1alpha1.PhaseDone {
+	// resolve the issue once the release recovered, or the phase was done
+	if releaser.Spec.Phase == devopsv1alpha1.PhaseDone || isSuccessful(releaser.Status.Conditions) {
+		if err = c.resolveIssue(releaser.DeepCopy()); err != nil {
+			c.logger.Error(err, "failed to resolve the issue", "releaser", req.NamespacedName)
+			result = ctrl.Result{
+				Requeue: true,
+			}
+		}
 		return
 	}
 
@@ -79,9 +89,13 @@ func (c *StatusController) Reconcile(ctx context.Context, req ctrl.Request) (res
 	return
 }
 
-func (c *StatusController) createOrUpdateIssue(conditions []devopsv1alpha1.Condition, releaser *devopsv1alpha1.Releaser) (
-	err error) {
+func (c *StatusController) getGitProvider(releaser *devopsv1alpha1.Releaser) (
+	gitProvider internal_scm.GitReleaser, err error) {
 	secretRef := releaser.GetGitOpsSecret()
+	if secretRef.Namespace == "" {
+		// the same as the Releaser by default
+		secretRef.Namespace = releaser.Namespace
+	}
 
 	secret := &v1.Secret{}
 	if err = c.Get(context.TODO(), types.NamespacedName{
@@ -93,19 +107,97 @@ func (c *StatusController) createOrUpdateIssue(conditions []devopsv1alpha1.Condi
 	}
 
 	repo := releaser.Spec.GitOps.Repository
-	gitProvider := getGitProviderClient(repo, secret)
-	if gitProvider == nil {
+	if gitProvider = getGitProviderClient(repo, secret); gitProvider == nil {
 		c.logger.Info(fmt.Sprintf("failed to get the git provider of %s", repo.Address))
+	}
+	return
+}
+
+func (c *StatusController) createOrUpdateIssue(conditions []devopsv1alpha1.Condition, releaser *devopsv1alpha1.Releaser) (
+	err error) {
+	var gitProvider internal_scm.GitReleaser
+	if gitProvider, err = c.getGitProvider(releaser); err != nil || gitProvider == nil {
 		return
 	}
 
-	var issueBody string
-	if issueBody, err = errorReportRender(releaser); err == nil {
-		err = gitProvider.CreateIssue(releaser.Name, issueBody)
+	var issueBody, number string
+	if issueBody, err = errorReportRender(releaser); err != nil {
+		return
 	}
+	ref := getIssueRef(releaser)
+	if number, err = gitProvider.CreateIssue(ref, fmt.Sprintf("%s\n%s\n", issueBody, ref.Marker)); err != nil ||
+		number == ref.Number {
+		return
+	}
+
+	// the labels are not essential, and not all the git providers support them
+	if labelErr := gitProvider.LabelIssue(number, []string{failureIssueLabel}); labelErr != nil {
+		c.logger.Info(fmt.Sprintf("failed to label the issue of %s, error: %v", releaser.Name, labelErr))
+	}
+	err = c.updateIssueStatus(releaser, &devopsv1alpha1.IssueStatus{Number: number, Title: ref.Title})
 	return
 }
 
+// resolveIssue comments on the issue of the release failure then closes it
+func (c *StatusController) resolveIssue(releaser *devopsv1alpha1.Releaser) (err error) {
+	// there is no issue to be resolved, no need to search it
+	if needNewIssue(releaser) {
+		return
+	}
+
+	var gitProvider internal_scm.GitReleaser
+	if gitProvider, err = c.getGitProvider(releaser); err != nil || gitProvider == nil {
+		return
+	}
+
+	comment := fmt.Sprintf("resolved, all the conditions of releaser %s are successful", releaser.Name)
+	if releaser.Spec.Phase == devopsv1alpha1.PhaseDone {
+		comment = fmt.Sprintf("resolved, releaser %s is done", releaser.Name)
+	}
+
+	issue := releaser.Status.Issue.DeepCopy()
+	var number string
+	if number, err = gitProvider.CloseIssue(getIssueRef(releaser), comment); err != nil {
+		return
+	}
+	if number != "" {
+		c.logger.Info("closed the issue", "releaser", releaser.Name, "issue", number)
+		issue.Number = number
+	}
+	// it might be closed by someone else if it was not found
+	issue.Closed = true
+	err = c.updateIssueStatus(releaser, issue)
+	return
+}
+
+// updateIssueStatus records the issue into the status of the Releaser
+func (c *StatusController) updateIssueStatus(releaser *devopsv1alpha1.Releaser, issue *devopsv1alpha1.IssueStatus) error {
+	original := releaser.DeepCopy()
+	releaser.Status.Issue = issue
+	return c.Status().Patch(context.TODO(), releaser, client.MergeFrom(original))
+}
+
+// getIssueRef returns the reference of the issue which reports the errors of a Releaser, the number of a closed issue
+// is not used because a new issue should be created for the new errors
+func getIssueRef(releaser *devopsv1alpha1.Releaser) (ref internal_scm.IssueRef) {
+	ref = internal_scm.IssueRef{
+		Title:  releaser.Name,
+		Marker: fmt.Sprintf("%s %s/%s -->", internal_scm.IssueMarkerPrefix, releaser.Namespace, releaser.Name),
+	}
+	if issue := releaser.Status.Issue; issue != nil && !issue.Closed {
+		ref.Number = issue.Number
+		if issue.Title != "" {
+			ref.Title = issue.Title
+		}
+	}
+	return
+}
+
+// needNewIssue checks if there is no open issue of the Releaser
+func needNewIssue(releaser *devopsv1alpha1.Releaser) bool {
+	return releaser.Status.Issue == nil || releaser.Status.Issue.Closed
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *StatusController) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
@@ -123,6 +215,14 @@ func errorReportRender(releaser *devopsv1alpha1.Releaser) (message string, err e
 {{- range .Status.Conditions}}
 |{{.ConditionType}}|{{.Status}}|{{trim .Message}}|
 {{- end}}
+{{- if .Status.Repositories}}
+
+|Repository|Version|Action|Status|Attempts|Commit|Error|
+|---|---|---|---|---|---|---|
+{{- range .Status.Repositories}}
+|{{.Address}}|{{.Version}}|{{.Action}}|{{.Status}}|{{.Attempts}}|{{.Commit}}|{{trim .LastError}}|
+{{- end}}
+{{- end}}
 `)
 	if err != nil {
 		return
@@ -149,3 +249,13 @@ func getFailedConditions(conditions []devopsv1alpha1.Condition) []devopsv1alpha1
 	}
 	return result
 }
+
+// isSuccessful checks if there are conditions and all of them are successful
+func isSuccessful(conditions []devopsv1alpha1.Condition) bool {
+	for i := range conditions {
+		if conditions[i].Status != devopsv1alpha1.ConditionStatusSuccess {
+			return false
+		}
+	}
+	return len(conditions) > 0
+}
@@ -1,8 +1,22 @@
 package controllers
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-logr/logr"
 	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"net/http"
+	"net/http/httptest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
 	"testing"
 )
 
@@ -48,3 +62,196 @@ message
 |other|failed|message|
 `, message)
 }
+
+func TestErrorReportRenderWithRepositories(t *testing.T) {
+	message, err := errorReportRender(&v1alpha1.Releaser{Status: v1alpha1.ReleaserStatus{
+		Conditions: []v1alpha1.Condition{{
+			ConditionType: v1alpha1.ConditionTypeRelease,
+			Status:        v1alpha1.ConditionStatusFailed,
+			Message:       "message",
+		}},
+		Repositories: []v1alpha1.RepositoryStatus{{
+			Address:  "https://github.com/x/a",
+			Version:  "v0.0.1",
+			Action:   v1alpha1.ActionTag,
+			Status:   v1alpha1.ConditionStatusSuccess,
+			Attempts: 1,
+			Commit:   "sha",
+		}, {
+			Address:   "https://github.com/x/b",
+			Version:   "v0.0.1",
+			Action:    v1alpha1.ActionRelease,
+			Status:    v1alpha1.ConditionStatusFailed,
+			Attempts:  2,
+			LastError: "error",
+		}},
+	}})
+	assert.Nil(t, err)
+	assert.Equal(t, `Errors found with releaser: 
+|Type|Status|Message|
+|---|---|---|
+|release|failed|message|
+
+|Repository|Version|Action|Status|Attempts|Commit|Error|
+|---|---|---|---|---|---|---|
+|https://github.com/x/a|v0.0.1|tag|success|1|sha||
+|https://github.com/x/b|v0.0.1|release|failed|2||error|
+`, message)
+}
+
+func TestIsSuccessful(t *testing.T) {
+	assert.False(t, isSuccessful(nil))
+	assert.True(t, isSuccessful([]v1alpha1.Condition{{Status: v1alpha1.ConditionStatusSuccess}}))
+	assert.False(t, isSuccessful([]v1alpha1.Condition{{
+		Status: v1alpha1.ConditionStatusSuccess,
+	}, {
+		Status: v1alpha1.ConditionStatusFailed,
+	}}))
+}
+
+func TestStatusController_Reconcile(t *testing.T) {
+	// a fake Gitee which has an open issue of the releaser
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		key := fmt.Sprintf("%s %s", req.Method, req.URL.Path)
+		requests = append(requests, key)
+		switch key {
+		case "GET /api/v5/repos/o/gitops/issues":
+			_ = json.NewEncoder(w).Encode([]map[string]string{
+				{"number": "I1", "title": "test", "body": "error\n<!-- ks-releaser: fake/test -->"},
+			})
+		case "GET /api/v5/repos/o/gitops/issues/I1":
+			_, _ = w.Write([]byte(`{"number":"I1","state":"open"}`))
+		default:
+			_, _ = w.Write([]byte("{}"))
+		}
+	}))
+	defer server.Close()
+
+	scheme := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(scheme))
+	assert.Nil(t, v1alpha1.AddToScheme(scheme))
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fake", Name: "gitops"},
+		Data:       map[string][]byte{"server": []byte(server.URL), corev1.BasicAuthPasswordKey: []byte("token")},
+	}
+	releaser := &v1alpha1.Releaser{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "fake", Name: "test"},
+		Spec: v1alpha1.ReleaserSpec{
+			Phase: v1alpha1.PhaseDraft,
+			GitOps: &v1alpha1.GitOps{
+				Enable: true,
+				Repository: v1alpha1.Repository{
+					Provider: v1alpha1.ProviderGitee,
+					Address:  server.URL + "/o/gitops",
+				},
+				Secret: corev1.SecretReference{Namespace: "fake", Name: "gitops"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		phase        v1alpha1.Phase
+		conditions   []v1alpha1.Condition
+		secretRef    corev1.SecretReference
+		issue        *v1alpha1.IssueStatus
+		wantRequests []string
+		wantIssue    *v1alpha1.IssueStatus
+	}{{
+		name: "without conditions",
+	}, {
+		name:       "failed",
+		conditions: []v1alpha1.Condition{{Status: v1alpha1.ConditionStatusFailed, Message: "error"}},
+		wantRequests: []string{
+			"GET /api/v5/repos/o/gitops/issues",
+			"POST /api/v5/repos/o/gitops/issues/I1/comments",
+			"POST /api/v5/repos/o/gitops/issues/I1/labels",
+		},
+		wantIssue: &v1alpha1.IssueStatus{Number: "I1", Title: "test"},
+	}, {
+		name:       "failed again",
+		conditions: []v1alpha1.Condition{{Status: v1alpha1.ConditionStatusFailed, Message: "error"}},
+		issue:      &v1alpha1.IssueStatus{Number: "I1", Title: "test"},
+		wantRequests: []string{
+			"GET /api/v5/repos/o/gitops/issues/I1",
+			"POST /api/v5/repos/o/gitops/issues/I1/comments",
+		},
+		wantIssue: &v1alpha1.IssueStatus{Number: "I1", Title: "test"},
+	}, {
+		name:       "failed without the recorded number",
+		conditions: []v1alpha1.Condition{{Status: v1alpha1.ConditionStatusFailed, Message: "error"}},
+		issue:      &v1alpha1.IssueStatus{Title: "test"},
+		wantRequests: []string{
+			"GET /api/v5/repos/o/gitops/issues",
+			"POST /api/v5/repos/o/gitops/issues/I1/comments",
+			"POST /api/v5/repos/o/gitops/issues/I1/labels",
+		},
+		wantIssue: &v1alpha1.IssueStatus{Number: "I1", Title: "test"},
+	}, {
+		name:       "recovered",
+		conditions: []v1alpha1.Condition{{Status: v1alpha1.ConditionStatusSuccess}},
+		issue:      &v1alpha1.IssueStatus{Number: "I1", Title: "test"},
+		wantRequests: []string{
+			"GET /api/v5/repos/o/gitops/issues/I1",
+			"POST /api/v5/repos/o/gitops/issues/I1/comments",
+			"PATCH /api/v5/repos/o/issues/I1",
+		},
+		wantIssue: &v1alpha1.IssueStatus{Number: "I1", Title: "test", Closed: true},
+	}, {
+		name:       "the namespace of the secret is omitted",
+		conditions: []v1alpha1.Condition{{Status: v1alpha1.ConditionStatusSuccess}},
+		secretRef:  corev1.SecretReference{Name: "gitops"},
+		issue:      &v1alpha1.IssueStatus{Number: "I1", Title: "test"},
+		wantRequests: []string{
+			"GET /api/v5/repos/o/gitops/issues/I1",
+			"POST /api/v5/repos/o/gitops/issues/I1/comments",
+			"PATCH /api/v5/repos/o/issues/I1",
+		},
+		wantIssue: &v1alpha1.IssueStatus{Number: "I1", Title: "test", Closed: true},
+	}, {
+		name:       "recovered without an issue",
+		conditions: []v1alpha1.Condition{{Status: v1alpha1.ConditionStatusSuccess}},
+	}, {
+		name:       "done",
+		phase:      v1alpha1.PhaseDone,
+		conditions: []v1alpha1.Condition{{Status: v1alpha1.ConditionStatusFailed, Message: "error"}},
+		issue:      &v1alpha1.IssueStatus{Number: "I1", Title: "test"},
+		wantRequests: []string{
+			"GET /api/v5/repos/o/gitops/issues/I1",
+			"POST /api/v5/repos/o/gitops/issues/I1/comments",
+			"PATCH /api/v5/repos/o/issues/I1",
+		},
+		wantIssue: &v1alpha1.IssueStatus{Number: "I1", Title: "test", Closed: true},
+	}, {
+		name:      "done and closed",
+		phase:     v1alpha1.PhaseDone,
+		issue:     &v1alpha1.IssueStatus{Number: "I1", Title: "test", Closed: true},
+		wantIssue: &v1alpha1.IssueStatus{Number: "I1", Title: "test", Closed: true},
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = nil
+			obj := releaser.DeepCopy()
+			if tt.phase != "" {
+				obj.Spec.Phase = tt.phase
+			}
+			obj.Status.Conditions = tt.conditions
+			obj.Status.Issue = tt.issue
+			if tt.secretRef.Name != "" {
+				obj.Spec.GitOps.Secret = tt.secretRef
+			}
+
+			c := &StatusController{Client: fake.NewFakeClientWithScheme(scheme, secret.DeepCopy(), obj)}
+			_, err := c.Reconcile(log.IntoContext(context.TODO(), logr.Discard()), ctrl.Request{
+				NamespacedName: types.NamespacedName{Namespace: "fake", Name: "test"},
+			})
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantRequests, requests)
+
+			result := &v1alpha1.Releaser{}
+			assert.Nil(t, c.Get(context.TODO(), types.NamespacedName{Namespace: "fake", Name: "test"}, result))
+			assert.Equal(t, tt.wantIssue, result.Status.Issue)
+		})
+	}
+}
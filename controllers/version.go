@@ -4,18 +4,39 @@ import (
 	"fmt"
 	"github.com/blang/semver"
 	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
 	"sigs.k8s.io/yaml"
 	"strings"
+	"time"
 )
 
-func isPreRelease(versionStr string) bool {
-	if version, err := semver.ParseTolerant(versionStr); err == nil {
-		return len(version.Pre) > 0
+// getVersioningScheme returns the versioning scheme of a Releaser, semver is the fallback of an invalid one
+func getVersioningScheme(releaser *devopsv1alpha1.Releaser, now func() time.Time) versioning.Scheme {
+	scheme, err := releaser.Spec.GetVersioningScheme(now)
+	if err != nil {
+		scheme = versioning.NewSemVer()
 	}
-	return false
+	return scheme
 }
 
-func bumpVersionTo(versionStr string, remainPre bool) (nextVersion string, isPre bool, err error) {
+func isPreRelease(scheme versioning.Scheme, versionStr string) bool {
+	return scheme.IsPreRelease(versionStr)
+}
+
+// getReleaseBranchName returns the name of the release branch, such as: release-1.2
+func getReleaseBranchName(scheme versioning.Scheme, versionStr string) (branch string, err error) {
+	branch, err = scheme.ReleaseBranch(versionStr)
+	return
+}
+
+func bumpVersionTo(scheme versioning.Scheme, versionStr string, remainPre bool) (nextVersion string, isPre bool, err error) {
+	nextVersion, isPre, err = scheme.Bump(versionStr, remainPre)
+	return
+}
+
+// bumpVersionWithStrategy bumps the version with the strategy, none keeps the version as it is
+func bumpVersionWithStrategy(versionStr string, strategy devopsv1alpha1.BumpStrategy,
+	preReleaseIdentifier string) (nextVersion string, err error) {
 	nextVersion = versionStr // keep using the old version if there's any problem happened
 
 	var version semver.Version
@@ -24,21 +45,46 @@ func bumpVersionTo(versionStr string, remainPre bool) (nextVersion string, isPre
 		return
 	}
 
-	if preVersionCount := len(version.Pre); preVersionCount > 0 {
-		isPre = true
-		if remainPre {
-			for i := preVersionCount - 1; i >= 0; i-- {
-				preVersion := &version.Pre[i]
-				if preVersion.IsNumeric() {
-					preVersion.VersionNum += 1
-					break
-				}
+	isPre := len(version.Pre) > 0
+	version.Build = nil
+	switch strategy {
+	case devopsv1alpha1.BumpStrategyPatch:
+		if !isPre {
+			version.Patch += 1
+		}
+		version.Pre = nil
+	case devopsv1alpha1.BumpStrategyMinor:
+		if !isPre || version.Patch != 0 {
+			version.Minor, version.Patch = version.Minor+1, 0
+		}
+		version.Pre = nil
+	case devopsv1alpha1.BumpStrategyMajor:
+		if !isPre || version.Minor != 0 || version.Patch != 0 {
+			version.Major, version.Minor, version.Patch = version.Major+1, 0, 0
+		}
+		version.Pre = nil
+	case devopsv1alpha1.BumpStrategyPreRelease:
+		if preReleaseIdentifier == "" {
+			err = fmt.Errorf("the pre-release identifier is required by strategy %s", strategy)
+			return
+		}
+		if isPre && version.Pre[0].VersionStr == preReleaseIdentifier {
+			if len(version.Pre) > 1 && version.Pre[len(version.Pre)-1].IsNumeric() {
+				version.Pre[len(version.Pre)-1].VersionNum += 1
+			} else {
+				version.Pre = append(version.Pre, semver.PRVersion{VersionNum: 0, IsNum: true})
 			}
 		} else {
-			version.Pre = nil
+			if !isPre {
+				version.Patch += 1
+			}
+			version.Pre = []semver.PRVersion{{VersionStr: preReleaseIdentifier}, {VersionNum: 0, IsNum: true}}
 		}
-	} else {
-		version.Patch += 1
+	case devopsv1alpha1.BumpStrategyNone:
+		return
+	default:
+		err = fmt.Errorf("unknown bump strategy: %s", strategy)
+		return
 	}
 
 	nextVersion = version.String()
@@ -48,16 +94,91 @@ func bumpVersionTo(versionStr string, remainPre bool) (nextVersion string, isPre
 	return
 }
 
+// getBumpStrategy returns the bump strategy of a Releaser, it is empty if the default one is used
+func getBumpStrategy(releaser *devopsv1alpha1.Releaser) (strategy devopsv1alpha1.BumpStrategy, preReleaseIdentifier string) {
+	if nextVersion := releaser.Spec.NextVersion; nextVersion != nil {
+		strategy, preReleaseIdentifier = nextVersion.Strategy, nextVersion.PreReleaseIdentifier
+	}
+	return
+}
+
+// hasNextReleaser checks if the next draft Releaser is needed
+func hasNextReleaser(releaser *devopsv1alpha1.Releaser) bool {
+	strategy, _ := getBumpStrategy(releaser)
+	return strategy != devopsv1alpha1.BumpStrategyNone
+}
+
 func bumpVersion(versionStr string) (nextVersion string, isPre bool, err error) {
-	nextVersion, isPre, err = bumpVersionTo(versionStr, true)
+	nextVersion, isPre, err = bumpVersionTo(versioning.NewSemVer(), versionStr, true)
 	return
 }
 
-func bumpReleaser(releaser *devopsv1alpha1.Releaser, remainPre bool) (isPre bool) {
-	var nextVersion string
+// getBumpScheme returns the versioning scheme which bumps the versions of a Releaser, ok is false if the versions
+// are bumped by a semver strategy. The strategies are ignored by calver.
+func getBumpScheme(releaser *devopsv1alpha1.Releaser, now func() time.Time) (scheme versioning.Scheme, ok bool, err error) {
+	strategy, _ := getBumpStrategy(releaser)
+	if ok = strategy == "" || isCalVer(releaser); ok {
+		scheme, err = releaser.Spec.GetVersioningScheme(now)
+	}
+	return
+}
+
+// isCalVer checks if a Releaser follows the calendar versioning
+func isCalVer(releaser *devopsv1alpha1.Releaser) bool {
+	return releaser.Spec.Versioning != nil && releaser.Spec.Versioning.Scheme == versioning.CalVer
+}
+
+// switchToCalVer replaces a semantic version with the first calendar version of the current period in the default
+// format, the prefix "v" is kept
+func switchToCalVer(versionStr string, now func() time.Time) (nextVersion string, err error) {
+	nextVersion = versionStr
+
+	if _, err = semver.ParseTolerant(versionStr); err != nil {
+		err = fmt.Errorf("cannot switch an invalid version to calver: %s, error: %v", versionStr, err)
+		return
+	}
+	var version string
+	if version, err = versioning.FirstCalVer("", now); err == nil {
+		if strings.HasPrefix(versionStr, "v") {
+			version = "v" + version
+		}
+		nextVersion = version
+	}
+	return
+}
+
+// bumpReleaser turns the Releaser into the next draft one. It returns true if an extra draft without the pre-release
+// is needed, this only happens when the versions are bumped by a versioning scheme. CalVer takes the current date
+// from the clock, the calver strategy switches a semver Releaser to calver.
+func bumpReleaser(releaser *devopsv1alpha1.Releaser, remainPre bool, now func() time.Time) (isPre bool, err error) {
+	strategy, preReleaseIdentifier := getBumpStrategy(releaser)
+	if strategy == devopsv1alpha1.BumpStrategyNone {
+		err = fmt.Errorf("no next Releaser is needed with strategy %s", strategy)
+		return
+	}
+
+	var scheme versioning.Scheme
+	var useScheme bool
+	if scheme, useScheme, err = getBumpScheme(releaser, now); err != nil {
+		return
+	}
+	switchingToCalVer := !useScheme && strategy == devopsv1alpha1.BumpStrategyCalVer
+	bump := func(versionStr string) (nextVersion string, isPre bool, err error) {
+		if useScheme {
+			nextVersion, isPre, err = bumpVersionTo(scheme, versionStr, remainPre)
+		} else if switchingToCalVer {
+			nextVersion, err = switchToCalVer(versionStr, now)
+		} else {
+			nextVersion, err = bumpVersionWithStrategy(versionStr, strategy, preReleaseIdentifier)
+		}
+		return
+	}
 
 	currentVersion := releaser.Spec.Version
-	nextVersion, isPre, _ = bumpVersionTo(currentVersion, remainPre)
+	var nextVersion string
+	if nextVersion, isPre, err = bump(currentVersion); err != nil {
+		return
+	}
 	if strings.HasSuffix(releaser.Name, currentVersion) {
 		nameWithoutVersion := strings.ReplaceAll(releaser.Name, currentVersion, "")
 		releaser.Name = nameWithoutVersion + nextVersion
@@ -74,7 +195,21 @@ func bumpReleaser(releaser *devopsv1alpha1.Releaser, remainPre bool) (isPre bool
 
 	for i, _ := range releaser.Spec.Repositories {
 		repo := &releaser.Spec.Repositories[i]
-		repo.Version, _, _ = bumpVersionTo(repo.Version, remainPre)
+		// the commit only belongs to the current release
+		repo.Commit = ""
+		if repo.Version == "" {
+			continue
+		}
+		if repo.Version, _, err = bump(repo.Version); err != nil {
+			err = fmt.Errorf("failed to bump the version of repository %s, error: %v", repo.Address, err)
+			return
+		}
+	}
+
+	if switchingToCalVer {
+		// the strategy is not needed anymore because the next one follows calver
+		releaser.Spec.Versioning = &devopsv1alpha1.Versioning{Scheme: versioning.CalVer}
+		releaser.Spec.NextVersion.Strategy = ""
 	}
 
 	// remove status
@@ -82,10 +217,16 @@ func bumpReleaser(releaser *devopsv1alpha1.Releaser, remainPre bool) (isPre bool
 	return
 }
 
-func bumpReleaserAsData(data []byte, remainPre bool) (result []byte, filename string, isPre bool, err error) {
+// bumpReleaserAsData bumps the Releaser from the YAML data, the result is empty if the next one is not needed
+func bumpReleaserAsData(data []byte, remainPre bool, now func() time.Time) (result []byte, filename string, isPre bool, err error) {
 	targetReleaser := &devopsv1alpha1.Releaser{}
 	if err = yaml.Unmarshal(data, targetReleaser); err == nil {
-		isPre = bumpReleaser(targetReleaser, remainPre)
+		if !hasNextReleaser(targetReleaser) {
+			return
+		}
+		if isPre, err = bumpReleaser(targetReleaser, remainPre, now); err != nil {
+			return
+		}
 		filename = targetReleaser.Name + ".yaml"
 		result, err = yaml.Marshal(targetReleaser)
 	}
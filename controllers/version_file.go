@@ -0,0 +1,279 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// versionCommitScope is used by the commit which updates the version files
+const versionCommitScope = "version"
+
+// updateVersionFiles writes the version into the files, then commits and pushes them into the branch.
+// It returns the new commit to be tagged.
+func updateVersionFiles(r *git.Repository, repo devopsv1alpha1.Repository, option releaseOption, target plumbing.Hash) (
+	newTarget plumbing.Hash, err error) {
+	newTarget = target
+
+	var head *plumbing.Reference
+	if head, err = r.Head(); err != nil {
+		return
+	}
+	if head.Hash() != target {
+		err = fmt.Errorf("cannot update the version files when tagging commit %s which is not the head of branch %s",
+			target.String(), repo.Branch)
+		return
+	}
+
+	var wd *git.Worktree
+	if wd, err = r.Worktree(); err != nil {
+		return
+	}
+	root := wd.Filesystem.Root()
+	for _, versionFile := range repo.VersionFiles {
+		if err = updateVersionFile(root, versionFile, repo.Version); err != nil {
+			err = fmt.Errorf("failed to update version file %s, error: %v", versionFile.Path, err)
+			return
+		}
+	}
+
+	var status git.Status
+	if status, err = wd.Status(); err != nil || status.IsClean() {
+		// the version files were updated by a previous attempt
+		return
+	}
+	commitMessage := fmt.Sprintf("%s(%s): release %s", changelogCommitType, versionCommitScope, repo.Version)
+	if err = addAndCommit(r, option.identity, commitMessage); err != nil {
+		return
+	}
+	if err = pushBranch(r, repo.Branch, getAuth(option.secret)); err != nil {
+		return
+	}
+
+	if head, err = r.Head(); err == nil {
+		newTarget = head.Hash()
+	}
+	return
+}
+
+// updateVersionFile replaces the version in a file by the pattern or the field
+func updateVersionFile(root string, versionFile devopsv1alpha1.VersionFile, version string) (err error) {
+	var filePath string
+	var data []byte
+	if filePath, data, err = replaceVersionFile(root, versionFile, version); err == nil {
+		err = ioutil.WriteFile(filePath, data, 0644)
+	}
+	return
+}
+
+// replaceVersionFile returns the path and the new content of a version file, nothing will be written
+func replaceVersionFile(root string, versionFile devopsv1alpha1.VersionFile, version string) (
+	filePath string, data []byte, err error) {
+	filePath = filepath.Join(root, versionFile.Path)
+	if !strings.HasPrefix(filePath, filepath.Clean(root)+string(filepath.Separator)) {
+		err = fmt.Errorf("file %s is out of the repository", versionFile.Path)
+		return
+	}
+	if versionFile.TrimPrefix {
+		version = strings.TrimPrefix(version, "v")
+	}
+
+	if data, err = ioutil.ReadFile(filePath); err != nil {
+		return
+	}
+	if versionFile.Field != "" {
+		data, err = replaceYAMLField(data, versionFile.Field, version)
+	} else {
+		data, err = replacePattern(data, versionFile.Pattern, version)
+	}
+	return
+}
+
+// replacePattern replaces the first group (or the whole match if there is no group) of all the matches
+func replacePattern(data []byte, pattern, version string) (result []byte, err error) {
+	var reg *regexp.Regexp
+	if reg, err = regexp.Compile(pattern); err != nil {
+		return
+	}
+
+	matches := reg.FindAllSubmatchIndex(data, -1)
+	if len(matches) == 0 {
+		err = fmt.Errorf("cannot find pattern %s", pattern)
+		return
+	}
+
+	last := 0
+	for _, match := range matches {
+		start, end := match[0], match[1]
+		if len(match) > 2 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		result = append(result, data[last:start]...)
+		result = append(result, version...)
+		last = end
+	}
+	result = append(result, data[last:]...)
+	return
+}
+
+// replaceYAMLField replaces the scalar of a YAML (or JSON) field in place, so the format of the file will be kept
+func replaceYAMLField(data []byte, field, version string) (result []byte, err error) {
+	doc := &yaml.Node{}
+	if err = yaml.Unmarshal(data, doc); err != nil {
+		return
+	}
+
+	var node *yaml.Node
+	if len(doc.Content) > 0 {
+		if node, err = findYAMLNode(doc.Content[0], field); err != nil {
+			return
+		}
+	}
+	if node == nil {
+		err = fmt.Errorf("cannot find field %s", field)
+		return
+	}
+
+	var edit yamlEdit
+	if edit, err = newScalarEdit(data, node, version); err != nil {
+		err = fmt.Errorf("cannot replace field %s, error: %v", field, err)
+		return
+	}
+	result = applyYAMLEdits(data, []yamlEdit{edit})
+	return
+}
+
+// yamlEdit replaces the bytes between start and end of the YAML data with the text
+type yamlEdit struct {
+	start, end int
+	text       string
+}
+
+// newScalarEdit replaces the value of a scalar node in place, the quotes of it will be kept
+func newScalarEdit(data []byte, node *yaml.Node, value string) (edit yamlEdit, err error) {
+	if edit.start = getOffset(data, node.Line, node.Column); edit.start < 0 {
+		err = errors.New("cannot find the value")
+		return
+	}
+
+	switch {
+	case node.Style&yaml.DoubleQuotedStyle != 0:
+		edit.end = getQuotedEnd(data, edit.start, '"')
+		edit.text = fmt.Sprintf(`"%s"`, value)
+	case node.Style&yaml.SingleQuotedStyle != 0:
+		edit.end = getQuotedEnd(data, edit.start, '\'')
+		edit.text = fmt.Sprintf("'%s'", value)
+	case node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		err = errors.New("the block scalar is not supported")
+		return
+	default:
+		edit.end = getPlainEnd(data, edit.start)
+		edit.text = value
+	}
+	if edit.end <= edit.start {
+		err = errors.New("cannot find the value")
+	}
+	return
+}
+
+// newMappingEntryEdit appends a key and its value after the last entry of a block or flow mapping
+func newMappingEntryEdit(data []byte, mapping *yaml.Node, key, value string) (edit yamlEdit, err error) {
+	count := len(mapping.Content)
+	if count < 2 || mapping.Content[count-1].Kind != yaml.ScalarNode {
+		err = errors.New("the mapping must end with a scalar")
+		return
+	}
+
+	var last yamlEdit
+	if last, err = newScalarEdit(data, mapping.Content[count-1], ""); err != nil {
+		return
+	}
+	if edit.start = last.end; mapping.Style&yaml.FlowStyle != 0 {
+		edit.text = fmt.Sprintf(", %s: %s", key, value)
+	} else {
+		// append a new line after the comment of the last entry, and indent it as the first key
+		for edit.start < len(data) && data[edit.start] != '\n' && data[edit.start] != '\r' {
+			edit.start++
+		}
+		edit.text = fmt.Sprintf("\n%s%s: %s", strings.Repeat(" ", mapping.Content[0].Column-1), key, value)
+	}
+	edit.end = edit.start
+	return
+}
+
+// applyYAMLEdits applies the edits from the end of the data, so the offsets of the others will not be changed.
+// The edit which overlaps with another one, such as the same field was updated twice, will be ignored.
+func applyYAMLEdits(data []byte, edits []yamlEdit) (result []byte) {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+
+	result = data
+	limit := len(data)
+	for _, edit := range edits {
+		if edit.end > limit {
+			continue
+		}
+		next := make([]byte, 0, len(result)+len(edit.text))
+		next = append(next, result[:edit.start]...)
+		next = append(next, edit.text...)
+		result = append(next, result[edit.end:]...)
+		limit = edit.start
+	}
+	return
+}
+
+// getOffset returns the offset of a position, the line and column start from 1
+func getOffset(data []byte, line, column int) (offset int) {
+	for ; line > 1 && offset < len(data); offset++ {
+		if data[offset] == '\n' {
+			line--
+		}
+	}
+	for ; column > 1 && offset < len(data); column-- {
+		_, size := utf8.DecodeRune(data[offset:])
+		offset += size
+	}
+	if offset >= len(data) {
+		offset = -1
+	}
+	return
+}
+
+// getQuotedEnd returns the offset after the closing quote
+func getQuotedEnd(data []byte, start int, quote byte) int {
+	for i := start + 1; i < len(data); i++ {
+		switch {
+		case quote == '"' && data[i] == '\\':
+			i++
+		case data[i] == quote && quote == '\'' && i+1 < len(data) && data[i+1] == '\'':
+			i++
+		case data[i] == quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// getPlainEnd returns the offset after a plain scalar, it ends before a comment, a line break or a flow indicator
+func getPlainEnd(data []byte, start int) (end int) {
+	for end = start; end < len(data); end++ {
+		c := data[end]
+		if c == '\n' || c == '\r' || c == ',' || c == ']' || c == '}' || (c == '#' && end > start && data[end-1] == ' ') {
+			break
+		}
+	}
+	for end > start && (data[end-1] == ' ' || data[end-1] == '\t') {
+		end--
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func Test_replacePattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		pattern string
+		want    string
+		wantErr bool
+	}{{
+		name:    "whole match",
+		data:    "v0.0.1\n",
+		pattern: `v[\d.]+`,
+		want:    "v1.0.0\n",
+	}, {
+		name:    "the first group",
+		data:    "package version\n\nconst Version = \"v0.0.1\"\nconst Other = \"v0.0.1\"\n",
+		pattern: `Version = "(.*)"`,
+		want:    "package version\n\nconst Version = \"v1.0.0\"\nconst Other = \"v0.0.1\"\n",
+	}, {
+		name:    "no matches",
+		data:    "v0.0.1",
+		pattern: `version: (.*)`,
+		wantErr: true,
+	}, {
+		name:    "invalid pattern",
+		data:    "v0.0.1",
+		pattern: `(`,
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := replacePattern([]byte(tt.data), tt.pattern, "v1.0.0")
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(result))
+		})
+	}
+}
+
+func Test_replaceYAMLField(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		field   string
+		want    string
+		wantErr bool
+	}{{
+		name:  "plain YAML scalar with a comment",
+		data:  "apiVersion: v2\nversion: 0.0.1 # the chart version\nappVersion: v0.0.1\n",
+		field: "version",
+		want:  "apiVersion: v2\nversion: 1.0.0 # the chart version\nappVersion: v0.0.1\n",
+	}, {
+		name:  "single quoted YAML scalar",
+		data:  "image:\n  tag: 'v0.0.1'\n",
+		field: "image.tag",
+		want:  "image:\n  tag: '1.0.0'\n",
+	}, {
+		name:  "JSON",
+		data:  "{\n  \"name\": \"console\",\n  \"version\": \"0.0.1\",\n  \"private\": true\n}\n",
+		field: "version",
+		want:  "{\n  \"name\": \"console\",\n  \"version\": \"1.0.0\",\n  \"private\": true\n}\n",
+	}, {
+		name:  "compact JSON",
+		data:  `{"versions":["0.0.1"]}`,
+		field: "versions[0]",
+		want:  `{"versions":["1.0.0"]}`,
+	}, {
+		name:    "block scalar",
+		data:    "version: |\n  0.0.1\n",
+		field:   "version",
+		wantErr: true,
+	}, {
+		name:    "missing field",
+		data:    "name: console\n",
+		field:   "version",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := replaceYAMLField([]byte(tt.data), tt.field, "1.0.0")
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(result))
+		})
+	}
+}
+
+func Test_tagRepositoryWithVersionFiles(t *testing.T) {
+	dir, remote := newLocalRepository(t, "first commit")
+	head, err := remote.Head()
+	assert.Nil(t, err)
+
+	repo := v1alpha1.Repository{
+		Address:      dir,
+		Branch:       "master",
+		Version:      "v0.0.1",
+		VersionFiles: []v1alpha1.VersionFile{{Path: "README.md", Pattern: ".+", TrimPrefix: true}},
+	}
+	option := releaseOption{identity: newGitIdentity("user"), cacheDir: t.TempDir()}
+	status := &v1alpha1.RepositoryStatus{}
+	err = tagRepository(repo, option, status)
+	assert.Nil(t, err)
+
+	// the version commit was pushed and tagged
+	newHead, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	assert.Nil(t, err)
+	assert.NotEqual(t, head.Hash(), newHead.Hash())
+	assert.Equal(t, newHead.Hash().String(), status.Commit)
+	commit, err := remote.CommitObject(newHead.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, "chore(version): release v0.0.1", commit.Message)
+	file, err := commit.File("README.md")
+	assert.Nil(t, err)
+	content, err := file.Contents()
+	assert.Nil(t, err)
+	assert.Equal(t, "0.0.1", content)
+
+	// the version commit is not a part of the release notes
+	commits, _, err := getCommitsSinceLastTag(remote, newHead.Hash(), "v0.0.1", versioning.NewSemVer())
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(commits))
+
+	// nothing to commit if the version files are up-to-date
+	repo.Version = "v0.0.1"
+	repo.OnExistingTag = v1alpha1.OnExistingTagVerify
+	err = tagRepository(repo, option, &v1alpha1.RepositoryStatus{})
+	assert.Nil(t, err)
+	latest, err := remote.Reference(plumbing.NewBranchReferenceName("master"), true)
+	assert.Nil(t, err)
+	assert.Equal(t, newHead.Hash(), latest.Hash())
+}
@@ -3,11 +3,13 @@ package controllers
 import (
 	"fmt"
 	devopsv1alpha1 "github.com/kubesphere-sigs/ks-releaser/api/v1alpha1"
+	"github.com/kubesphere-sigs/ks-releaser/internal/versioning"
 	"github.com/stretchr/testify/assert"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestVersionBump(t *testing.T) {
@@ -106,6 +108,7 @@ func Test_bumpReleaser(t *testing.T) {
 					Version: "v1.0.1",
 					Repositories: []devopsv1alpha1.Repository{{
 						Version: "v1.2.3",
+						Commit:  "a-fake-commit",
 					}},
 				},
 			}},
@@ -134,11 +137,48 @@ func Test_bumpReleaser(t *testing.T) {
 				Phase:   devopsv1alpha1.PhaseDraft,
 				Version: "v1.0.2"},
 		},
+	}, {
+		name: "invalid version of a repository",
+		args: args{
+			releaser: &devopsv1alpha1.Releaser{
+				Spec: devopsv1alpha1.ReleaserSpec{
+					Version:      "v1.0.1",
+					Repositories: []devopsv1alpha1.Repository{{Address: "fake", Version: "abc"}},
+				},
+			},
+		},
+		wantErr: true,
+	}, {
+		name: "prerelease without identifier",
+		args: args{
+			releaser: &devopsv1alpha1.Releaser{
+				Spec: devopsv1alpha1.ReleaserSpec{
+					Version:     "v1.0.1",
+					NextVersion: &devopsv1alpha1.NextVersion{Strategy: devopsv1alpha1.BumpStrategyPreRelease},
+				},
+			},
+		},
+		wantErr: true,
+	}, {
+		name: "none",
+		args: args{
+			releaser: &devopsv1alpha1.Releaser{
+				Spec: devopsv1alpha1.ReleaserSpec{
+					Version:     "v1.0.1",
+					NextVersion: &devopsv1alpha1.NextVersion{Strategy: devopsv1alpha1.BumpStrategyNone},
+				},
+			},
+		},
+		wantErr: true,
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			bumpReleaser(tt.args.releaser, true)
-			if !reflect.DeepEqual(tt.args.releaser, tt.wantResult) {
+			_, err := bumpReleaser(tt.args.releaser, true, time.Now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("bumpReleaser() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.args.releaser, tt.wantResult) {
 				t.Errorf("bumpReleaser() gotResult = %v, want %v", tt.args.releaser, tt.wantResult)
 			}
 		})
@@ -177,10 +217,54 @@ spec:
 status: {}
 `,
 		wantErr: false,
+	}, {
+		name: "minor strategy",
+		args: args{
+			data: `apiVersion: devops.kubesphere.io/v1alpha1
+kind: Releaser
+metadata:
+  name: ks-releaser-v0.1.0-rc.1
+spec:
+  nextVersion:
+    strategy: minor
+  repositories:
+  - version: v0.1.0-rc.1
+  version: v0.1.0-rc.1`,
+		},
+		wantResult: `apiVersion: devops.kubesphere.io/v1alpha1
+kind: Releaser
+metadata:
+  creationTimestamp: null
+  name: ks-releaser-v0.1.0
+spec:
+  nextVersion:
+    strategy: minor
+  phase: draft
+  repositories:
+  - address: ""
+    name: ""
+    version: v0.1.0
+  secret: {}
+  version: v0.1.0
+status: {}
+`,
+	}, {
+		name: "none strategy",
+		args: args{
+			data: `apiVersion: devops.kubesphere.io/v1alpha1
+kind: Releaser
+metadata:
+  name: ks-releaser-v0.1.0
+spec:
+  nextVersion:
+    strategy: none
+  version: v0.1.0`,
+		},
+		wantResult: "",
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotResult, _, _, err := bumpReleaserAsData([]byte(tt.args.data), true)
+			gotResult, _, _, err := bumpReleaserAsData([]byte(tt.args.data), true, time.Now)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("bumpReleaserAsData() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -233,7 +317,7 @@ func Test_isPreRelease(t *testing.T) {
 	}}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := isPreRelease(tt.args.versionStr); got != tt.want {
+			if got := isPreRelease(versioning.NewSemVer(), tt.args.versionStr); got != tt.want {
 				t.Errorf("isPreRelease() = %v, want %v", got, tt.want)
 			}
 		})
@@ -267,7 +351,7 @@ func Test_bumpVersionTo(t *testing.T) {
 	for i, _ := range testCases {
 		caseItem := testCases[i]
 
-		nextVersion, _, err := bumpVersionTo(caseItem.arg.version, caseItem.arg.remainPre)
+		nextVersion, _, err := bumpVersionTo(versioning.NewSemVer(), caseItem.arg.version, caseItem.arg.remainPre)
 		if caseItem.wantErr {
 			assert.NotNil(t, err, fmt.Sprintf("test failed with case[%d]", i))
 		}
@@ -275,3 +359,184 @@ func Test_bumpVersionTo(t *testing.T) {
 		assert.Equal(t, caseItem.wantVersion, nextVersion, fmt.Sprintf("test failed with case[%d]", i))
 	}
 }
+
+func Test_getReleaseBranchName(t *testing.T) {
+	branch, err := getReleaseBranchName(versioning.NewSemVer(), "v1.2.3")
+	assert.Nil(t, err)
+	assert.Equal(t, "release-1.2", branch)
+
+	branch, err = getReleaseBranchName(versioning.NewSemVer(), "v3.2.0-alpha.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "release-3.2", branch)
+
+	_, err = getReleaseBranchName(versioning.NewSemVer(), "fake")
+	assert.NotNil(t, err)
+
+	calVer, err := versioning.NewCalVer("")
+	assert.Nil(t, err)
+	branch, err = getReleaseBranchName(calVer, "v2021.09.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "release-2021.09", branch)
+}
+
+func Test_bumpVersionWithStrategy(t *testing.T) {
+	tests := []struct {
+		name                 string
+		version              string
+		strategy             devopsv1alpha1.BumpStrategy
+		preReleaseIdentifier string
+		want                 string
+		wantErr              bool
+	}{{
+		name:     "invalid version",
+		version:  "abc",
+		strategy: devopsv1alpha1.BumpStrategyPatch,
+		want:     "abc",
+		wantErr:  true,
+	}, {
+		name:     "unknown strategy",
+		version:  "v1.2.3",
+		strategy: "fake",
+		want:     "v1.2.3",
+		wantErr:  true,
+	}, {
+		name:     "patch",
+		version:  "v1.2.3",
+		strategy: devopsv1alpha1.BumpStrategyPatch,
+		want:     "v1.2.4",
+	}, {
+		name:     "patch from a pre-release",
+		version:  "v1.2.3-rc.1",
+		strategy: devopsv1alpha1.BumpStrategyPatch,
+		want:     "v1.2.3",
+	}, {
+		name:     "minor",
+		version:  "v1.2.3",
+		strategy: devopsv1alpha1.BumpStrategyMinor,
+		want:     "v1.3.0",
+	}, {
+		name:     "minor from a pre-release",
+		version:  "v1.3.0-alpha.1",
+		strategy: devopsv1alpha1.BumpStrategyMinor,
+		want:     "v1.3.0",
+	}, {
+		name:     "major",
+		version:  "1.2.3",
+		strategy: devopsv1alpha1.BumpStrategyMajor,
+		want:     "2.0.0",
+	}, {
+		name:     "major from a pre-release",
+		version:  "v2.0.0-rc.0",
+		strategy: devopsv1alpha1.BumpStrategyMajor,
+		want:     "v2.0.0",
+	}, {
+		name:                 "prerelease from a release",
+		version:              "v1.2.3",
+		strategy:             devopsv1alpha1.BumpStrategyPreRelease,
+		preReleaseIdentifier: "alpha",
+		want:                 "v1.2.4-alpha.0",
+	}, {
+		name:                 "prerelease with the same identifier",
+		version:              "v1.3.0-alpha.1",
+		strategy:             devopsv1alpha1.BumpStrategyPreRelease,
+		preReleaseIdentifier: "alpha",
+		want:                 "v1.3.0-alpha.2",
+	}, {
+		name:                 "prerelease with another identifier",
+		version:              "v1.3.0-alpha.1",
+		strategy:             devopsv1alpha1.BumpStrategyPreRelease,
+		preReleaseIdentifier: "rc",
+		want:                 "v1.3.0-rc.0",
+	}, {
+		name:     "prerelease without identifier",
+		version:  "v1.3.0",
+		strategy: devopsv1alpha1.BumpStrategyPreRelease,
+		want:     "v1.3.0",
+		wantErr:  true,
+	}, {
+		name:     "none",
+		version:  "v1.2.3",
+		strategy: devopsv1alpha1.BumpStrategyNone,
+		want:     "v1.2.3",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bumpVersionWithStrategy(tt.version, tt.strategy, tt.preReleaseIdentifier)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_hasNextReleaser(t *testing.T) {
+	releaser := &devopsv1alpha1.Releaser{}
+	assert.True(t, hasNextReleaser(releaser))
+
+	releaser.Spec.NextVersion = &devopsv1alpha1.NextVersion{Strategy: devopsv1alpha1.BumpStrategyNone}
+	assert.False(t, hasNextReleaser(releaser))
+
+	// only one draft is needed with a strategy
+	releaser.Spec.Version = "v1.0.0-rc.0"
+	releaser.Spec.NextVersion.Strategy = devopsv1alpha1.BumpStrategyPreRelease
+	releaser.Spec.NextVersion.PreReleaseIdentifier = "rc"
+	isPre, err := bumpReleaser(releaser, true, time.Now)
+	assert.Nil(t, err)
+	assert.False(t, isPre)
+	assert.Equal(t, "v1.0.0-rc.1", releaser.Spec.Version)
+}
+
+func Test_bumpReleaserWithCalVer(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	}
+	releaser := &devopsv1alpha1.Releaser{
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Version:      "v2020.01.3",
+			Versioning:   &devopsv1alpha1.Versioning{Scheme: versioning.CalVer},
+			NextVersion:  &devopsv1alpha1.NextVersion{Strategy: devopsv1alpha1.BumpStrategyMinor},
+			Repositories: []devopsv1alpha1.Repository{{Version: "2020.01.3-rc.0"}},
+		},
+	}
+	isPre, err := bumpReleaser(releaser, true, now)
+	assert.Nil(t, err)
+	assert.False(t, isPre)
+
+	// the strategy is ignored by calver
+	assert.Equal(t, "v2021.09.0", releaser.Spec.Version)
+	assert.Equal(t, "2020.01.3-rc.1", releaser.Spec.Repositories[0].Version)
+
+	// the calver strategy switches a semver Releaser to calver in the default format
+	releaser = &devopsv1alpha1.Releaser{
+		ObjectMeta: metav1.ObjectMeta{Name: "fake-v1.2.3"},
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Version: "v1.2.3",
+			NextVersion: &devopsv1alpha1.NextVersion{
+				Strategy: devopsv1alpha1.BumpStrategyCalVer, InferFromCommits: true,
+			},
+			Repositories: []devopsv1alpha1.Repository{{Version: "1.2.3-rc.0"}, {}},
+		},
+	}
+	isPre, err = bumpReleaser(releaser, true, now)
+	assert.Nil(t, err)
+	assert.False(t, isPre)
+	assert.Equal(t, "fake-v2021.09.0", releaser.Name)
+	assert.Equal(t, "v2021.09.0", releaser.Spec.Version)
+	assert.Equal(t, "2021.09.0", releaser.Spec.Repositories[0].Version)
+	assert.Equal(t, "", releaser.Spec.Repositories[1].Version)
+	assert.Equal(t, &devopsv1alpha1.Versioning{Scheme: versioning.CalVer}, releaser.Spec.Versioning)
+	assert.Equal(t, &devopsv1alpha1.NextVersion{InferFromCommits: true}, releaser.Spec.NextVersion)
+
+	// then it is bumped by calver
+	_, err = bumpReleaser(releaser, true, now)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2021.09.1", releaser.Spec.Version)
+
+	releaser = &devopsv1alpha1.Releaser{
+		Spec: devopsv1alpha1.ReleaserSpec{
+			Version:     "abc",
+			NextVersion: &devopsv1alpha1.NextVersion{Strategy: devopsv1alpha1.BumpStrategyCalVer},
+		},
+	}
+	_, err = bumpReleaser(releaser, true, now)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,347 @@
+package versioning
+
+import (
+	"fmt"
+	"github.com/blang/semver"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCalVerFormat is the default format of CalVer, such as: 2021.09.0
+const DefaultCalVerFormat = "YYYY.0M.MICRO"
+
+// calVerTokens are the supported tokens of the format, the longer ones must be in front of their prefixes
+var calVerTokens = []string{"YYYY", "0Y", "YY", "0M", "MM", "0W", "WW", "0D", "DD", "MAJOR", "MINOR", "MICRO"}
+
+// calVerDateTokens are the tokens which come from the date
+var calVerDateTokens = map[string]bool{
+	"YYYY": true, "0Y": true, "YY": true, "0M": true, "MM": true, "0W": true, "WW": true, "0D": true, "DD": true,
+}
+
+type calVer struct {
+	format  string
+	tokens  []string
+	pattern *regexp.Regexp
+	now     func() time.Time
+}
+
+// calVerVersion is a parsed calendar version
+type calVerVersion struct {
+	prefix   string
+	values   []int
+	modifier string
+}
+
+// NewCalVer creates the calendar versioning scheme with the format, such as: YYYY.0M.MICRO, YY.0M.MICRO.
+// The prefix "v" and the modifier (pre-release), such as: 2021.09.0-rc.1, are allowed.
+func NewCalVer(format string) (Scheme, error) {
+	return NewCalVerWithClock(format, time.Now)
+}
+
+// NewCalVerWithClock creates the calendar versioning scheme which takes the current date from the clock
+func NewCalVerWithClock(format string, now func() time.Time) (Scheme, error) {
+	return newCalVer(format, now)
+}
+
+// FirstCalVer returns the first calendar version of the current period in the format, such as: 2021.09.0.
+// The counters (MAJOR, MINOR and MICRO) start from 0.
+func FirstCalVer(format string, now func() time.Time) (version string, err error) {
+	var scheme *calVer
+	if scheme, err = newCalVer(format, now); err != nil {
+		return
+	}
+
+	first := calVerVersion{values: make([]int, len(scheme.tokens))}
+	for i, token := range scheme.tokens {
+		if calVerDateTokens[token] {
+			first.values[i] = getDateValue(token, now())
+		}
+	}
+	version = scheme.render(first)
+	return
+}
+
+func newCalVer(format string, now func() time.Time) (scheme *calVer, err error) {
+	if format == "" {
+		format = DefaultCalVerFormat
+	}
+	scheme = &calVer{format: format, now: now}
+
+	var hasDate bool
+	pattern := &strings.Builder{}
+	pattern.WriteString(`^(v?)`)
+	for rest := format; rest != ""; {
+		var matched string
+		for _, token := range calVerTokens {
+			if strings.HasPrefix(rest, token) {
+				matched = token
+				break
+			}
+		}
+
+		if matched == "" {
+			pattern.WriteString(regexp.QuoteMeta(rest[:1]))
+			rest = rest[1:]
+			continue
+		}
+		hasDate = hasDate || calVerDateTokens[matched]
+		scheme.tokens = append(scheme.tokens, matched)
+		pattern.WriteString(`(\d+)`)
+		rest = rest[len(matched):]
+	}
+	pattern.WriteString(`(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+	if !hasDate {
+		err = fmt.Errorf("invalid calendar versioning format: %s, at least one date token is required", format)
+		return
+	}
+	scheme.pattern, err = regexp.Compile(pattern.String())
+	return
+}
+
+func (c *calVer) parse(versionStr string) (version calVerVersion, err error) {
+	groups := c.pattern.FindStringSubmatch(versionStr)
+	if groups == nil {
+		err = fmt.Errorf("invalid calendar version: %s, the format is %s", versionStr, c.format)
+		return
+	}
+
+	version.prefix = groups[1]
+	version.modifier = groups[len(groups)-1]
+	for i, token := range c.tokens {
+		text := groups[i+2]
+		if strings.HasPrefix(token, "0") && len(text) < 2 {
+			err = fmt.Errorf("invalid calendar version: %s, %s requires two digits at least", versionStr, token)
+			return
+		}
+
+		var value int
+		if value, err = strconv.Atoi(text); err != nil {
+			return
+		}
+		version.values = append(version.values, value)
+	}
+	return
+}
+
+func (c *calVer) render(version calVerVersion) string {
+	result := &strings.Builder{}
+	result.WriteString(version.prefix)
+
+	index := 0
+	for rest := c.format; rest != ""; {
+		if index < len(c.tokens) && strings.HasPrefix(rest, c.tokens[index]) {
+			token := c.tokens[index]
+			result.WriteString(renderValue(token, version.values[index]))
+			rest = rest[len(token):]
+			index++
+			continue
+		}
+		result.WriteString(rest[:1])
+		rest = rest[1:]
+	}
+
+	if version.modifier != "" {
+		result.WriteString("-" + version.modifier)
+	}
+	return result.String()
+}
+
+// renderValue renders the value of a token, the tokens which start with "0" are padded to two digits
+func renderValue(token string, value int) string {
+	if strings.HasPrefix(token, "0") {
+		return fmt.Sprintf("%02d", value)
+	}
+	return strconv.Itoa(value)
+}
+
+// getDateValue returns the value of a date token
+func getDateValue(token string, now time.Time) int {
+	switch token {
+	case "YYYY":
+		return now.Year()
+	case "YY", "0Y":
+		return now.Year() - 2000
+	case "MM", "0M":
+		return int(now.Month())
+	case "WW", "0W":
+		_, week := now.ISOWeek()
+		return week
+	default:
+		return now.Day()
+	}
+}
+
+// Validate checks if the version matches the format
+func (c *calVer) Validate(version string) (err error) {
+	_, err = c.parse(version)
+	return
+}
+
+// IsPreRelease checks if the version has the modifier
+func (c *calVer) IsPreRelease(versionStr string) bool {
+	version, err := c.parse(versionStr)
+	return err == nil && version.modifier != ""
+}
+
+// Bump moves the version to the current date. The micro (or minor, major) number is bumped if the date is not changed,
+// or the micro number will be reset.
+func (c *calVer) Bump(versionStr string, remainPre bool) (nextVersion string, isPre bool, err error) {
+	nextVersion = versionStr // keep using the old version if there's any problem happened
+
+	var version calVerVersion
+	if version, err = c.parse(versionStr); err != nil {
+		err = fmt.Errorf("cannot bump an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+
+	if version.modifier != "" {
+		isPre = true
+		if remainPre {
+			identifiers := strings.Split(version.modifier, ".")
+			for i := len(identifiers) - 1; i >= 0; i-- {
+				if number, numErr := strconv.Atoi(identifiers[i]); numErr == nil {
+					identifiers[i] = strconv.Itoa(number + 1)
+					break
+				}
+			}
+			version.modifier = strings.Join(identifiers, ".")
+		} else {
+			version.modifier = ""
+		}
+		nextVersion = c.render(version)
+		return
+	}
+
+	nextVersion, err = c.moveDate(version, versionStr)
+	return
+}
+
+// moveDate moves the version without the modifier to the current date, or bumps the counter in the same period
+func (c *calVer) moveDate(version calVerVersion, versionStr string) (nextVersion string, err error) {
+	nextVersion = versionStr
+
+	now := c.now()
+	dateChanged := false
+	counters := map[string]int{}
+	for i, token := range c.tokens {
+		if calVerDateTokens[token] {
+			value := getDateValue(token, now)
+			dateChanged = dateChanged || version.values[i] != value
+			version.values[i] = value
+		} else {
+			counters[token] = i
+		}
+	}
+
+	if dateChanged {
+		if index, ok := counters["MICRO"]; ok {
+			version.values[index] = 0
+		}
+	} else {
+		bumped := false
+		for _, token := range []string{"MICRO", "MINOR", "MAJOR"} {
+			if index, ok := counters[token]; ok {
+				version.values[index] += 1
+				bumped = true
+				break
+			}
+		}
+		if !bumped {
+			err = fmt.Errorf("cannot bump version %s in the same period without MAJOR, MINOR or MICRO", versionStr)
+			return
+		}
+	}
+	nextVersion = c.render(version)
+	return
+}
+
+// Increase drops the modifier and moves the version to the current date, the level of the changes is ignored
+func (c *calVer) Increase(versionStr string, level Level) (nextVersion string, err error) {
+	nextVersion = versionStr
+
+	var version calVerVersion
+	if version, err = c.parse(versionStr); err != nil {
+		err = fmt.Errorf("cannot increase an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+	version.modifier = ""
+	nextVersion, err = c.moveDate(version, versionStr)
+	return
+}
+
+// ReleaseBranch returns the release branch of the period, such as: release-2021.09. The MICRO number and the separator
+// in front of it are not a part of the branch name.
+func (c *calVer) ReleaseBranch(versionStr string) (branch string, err error) {
+	var version calVerVersion
+	if version, err = c.parse(versionStr); err != nil {
+		err = fmt.Errorf("cannot get the release branch of an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+
+	name := &strings.Builder{}
+	separator := &strings.Builder{}
+	index := 0
+	for rest := c.format; rest != ""; {
+		if index < len(c.tokens) && strings.HasPrefix(rest, c.tokens[index]) {
+			token := c.tokens[index]
+			if token != "MICRO" {
+				name.WriteString(separator.String())
+				name.WriteString(renderValue(token, version.values[index]))
+			}
+			separator.Reset()
+			rest = rest[len(token):]
+			index++
+			continue
+		}
+		separator.WriteString(rest[:1])
+		rest = rest[1:]
+	}
+	branch = "release-" + name.String()
+	return
+}
+
+// Compare compares the numbers of two calendar versions one by one, a version with the modifier is lower than the one
+// without it
+func (c *calVer) Compare(versionStr, otherStr string) (result int, err error) {
+	var version, other calVerVersion
+	if version, err = c.parse(versionStr); err != nil {
+		err = fmt.Errorf("cannot compare an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+	if other, err = c.parse(otherStr); err != nil {
+		err = fmt.Errorf("cannot compare an invalid version: %s, error: %v", otherStr, err)
+		return
+	}
+
+	for i := range version.values {
+		switch {
+		case version.values[i] < other.values[i]:
+			return -1, nil
+		case version.values[i] > other.values[i]:
+			return 1, nil
+		}
+	}
+	result = compareModifiers(version.modifier, other.modifier)
+	return
+}
+
+// compareModifiers compares the modifiers as the pre-release parts of the semantic versions
+func compareModifiers(modifier, other string) int {
+	switch {
+	case modifier == other:
+		return 0
+	case modifier == "":
+		return 1
+	case other == "":
+		return -1
+	}
+	version, err := semver.Parse("0.0.0-" + modifier)
+	otherVersion, otherErr := semver.Parse("0.0.0-" + other)
+	if err != nil || otherErr != nil {
+		return strings.Compare(modifier, other)
+	}
+	return version.Compare(otherVersion)
+}
@@ -0,0 +1,60 @@
+package versioning
+
+import (
+	"fmt"
+	"time"
+)
+
+// Scheme is the abstraction of a versioning scheme, such as: SemVer, CalVer
+type Scheme interface {
+	// Validate checks if the version follows the scheme
+	Validate(version string) error
+	// IsPreRelease checks if the version is a pre-release one, such as: v1.2.0-rc.1
+	IsPreRelease(version string) bool
+	// Bump returns the next version. The pre-release number will be bumped if remainPre is true,
+	// or the pre-release will be dropped. isPre is true if the current version is a pre-release one.
+	Bump(version string, remainPre bool) (nextVersion string, isPre bool, err error)
+	// Increase returns the next release version after the changes of the level, the pre-release will be dropped
+	Increase(version string, level Level) (nextVersion string, err error)
+	// ReleaseBranch returns the name of the release branch of the version, such as: release-1.2
+	ReleaseBranch(version string) (branch string, err error)
+	// Compare returns -1, 0 or 1 if the version is lower than, equal to or higher than the other one
+	Compare(version, other string) (result int, err error)
+}
+
+// Level is the level of the changes between two versions
+type Level int
+
+const (
+	// LevelPatch is the level of the bug fixes
+	LevelPatch Level = iota
+	// LevelMinor is the level of the features
+	LevelMinor
+	// LevelMajor is the level of the breaking changes
+	LevelMajor
+)
+
+const (
+	// SemVer is the name of the semantic versioning scheme, see also https://semver.org/
+	SemVer = "semver"
+	// CalVer is the name of the calendar versioning scheme, see also https://calver.org/
+	CalVer = "calver"
+)
+
+// NewScheme creates a versioning scheme by the name, the format is only available for CalVer
+func NewScheme(name, format string) (Scheme, error) {
+	return NewSchemeWithClock(name, format, time.Now)
+}
+
+// NewSchemeWithClock creates a versioning scheme by the name, CalVer takes the current date from the clock
+func NewSchemeWithClock(name, format string, now func() time.Time) (scheme Scheme, err error) {
+	switch name {
+	case "", SemVer:
+		scheme = NewSemVer()
+	case CalVer:
+		scheme, err = NewCalVerWithClock(format, now)
+	default:
+		err = fmt.Errorf("unknown versioning scheme: %s", name)
+	}
+	return
+}
@@ -0,0 +1,119 @@
+package versioning
+
+import (
+	"fmt"
+	"github.com/blang/semver"
+	"strings"
+)
+
+type semVer struct{}
+
+// NewSemVer creates the semantic versioning scheme, the prefix "v" is allowed
+func NewSemVer() Scheme {
+	return semVer{}
+}
+
+// Validate checks if the version is a valid semantic version
+func (s semVer) Validate(version string) (err error) {
+	if _, err = semver.ParseTolerant(version); err != nil {
+		err = fmt.Errorf("invalid semantic version: %s, error: %v", version, err)
+	}
+	return
+}
+
+// IsPreRelease checks if the version has the pre-release part
+func (s semVer) IsPreRelease(versionStr string) bool {
+	if version, err := semver.ParseTolerant(versionStr); err == nil {
+		return len(version.Pre) > 0
+	}
+	return false
+}
+
+// Bump bumps the last numeric pre-release identifier or the patch number
+func (s semVer) Bump(versionStr string, remainPre bool) (nextVersion string, isPre bool, err error) {
+	nextVersion = versionStr // keep using the old version if there's any problem happened
+
+	var version semver.Version
+	if version, err = semver.ParseTolerant(versionStr); err != nil {
+		err = fmt.Errorf("cannot bump an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+
+	if preVersionCount := len(version.Pre); preVersionCount > 0 {
+		isPre = true
+		if remainPre {
+			for i := preVersionCount - 1; i >= 0; i-- {
+				preVersion := &version.Pre[i]
+				if preVersion.IsNumeric() {
+					preVersion.VersionNum += 1
+					break
+				}
+			}
+		} else {
+			version.Pre = nil
+		}
+	} else {
+		version.Patch += 1
+	}
+
+	nextVersion = version.String()
+	if strings.HasPrefix(versionStr, "v") {
+		nextVersion = "v" + nextVersion
+	}
+	return
+}
+
+// Increase bumps the major number for the breaking changes (or the minor number before 1.0.0), the minor number for
+// the features, or the patch number
+func (s semVer) Increase(versionStr string, level Level) (nextVersion string, err error) {
+	nextVersion = versionStr
+
+	var version semver.Version
+	if version, err = semver.ParseTolerant(versionStr); err != nil {
+		err = fmt.Errorf("cannot increase an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+
+	version.Pre = nil
+	version.Build = nil
+	switch {
+	case level == LevelMajor && version.Major > 0:
+		version.Major, version.Minor, version.Patch = version.Major+1, 0, 0
+	case level == LevelMajor || level == LevelMinor:
+		version.Minor, version.Patch = version.Minor+1, 0
+	default:
+		version.Patch += 1
+	}
+
+	nextVersion = version.String()
+	if strings.HasPrefix(versionStr, "v") {
+		nextVersion = "v" + nextVersion
+	}
+	return
+}
+
+// Compare compares two semantic versions, the prefix "v" is ignored
+func (s semVer) Compare(versionStr, otherStr string) (result int, err error) {
+	var version, other semver.Version
+	if version, err = semver.ParseTolerant(versionStr); err != nil {
+		err = fmt.Errorf("cannot compare an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+	if other, err = semver.ParseTolerant(otherStr); err != nil {
+		err = fmt.Errorf("cannot compare an invalid version: %s, error: %v", otherStr, err)
+		return
+	}
+	result = version.Compare(other)
+	return
+}
+
+// ReleaseBranch returns the release branch of the minor version, such as: release-1.2
+func (s semVer) ReleaseBranch(versionStr string) (branch string, err error) {
+	var version semver.Version
+	if version, err = semver.ParseTolerant(versionStr); err != nil {
+		err = fmt.Errorf("cannot get the release branch of an invalid version: %s, error: %v", versionStr, err)
+		return
+	}
+	branch = fmt.Sprintf("release-%d.%d", version.Major, version.Minor)
+	return
+}
@@ -0,0 +1,335 @@
+package versioning
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewScheme(t *testing.T) {
+	scheme, err := NewScheme("", "")
+	assert.Nil(t, err)
+	assert.Equal(t, NewSemVer(), scheme)
+
+	scheme, err = NewScheme(CalVer, "")
+	assert.Nil(t, err)
+	assert.Nil(t, scheme.Validate("2021.09.0"))
+
+	_, err = NewScheme(CalVer, "MAJOR.MINOR")
+	assert.NotNil(t, err)
+
+	_, err = NewScheme("fake", "")
+	assert.NotNil(t, err)
+
+	scheme, err = NewSchemeWithClock(CalVer, "", func() time.Time {
+		return time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	})
+	assert.Nil(t, err)
+	nextVersion, _, err := scheme.Bump("2021.08.3", true)
+	assert.Nil(t, err)
+	assert.Equal(t, "2021.09.0", nextVersion)
+}
+
+func TestSemVer(t *testing.T) {
+	scheme := NewSemVer()
+	assert.Nil(t, scheme.Validate("v1.2.3"))
+	assert.NotNil(t, scheme.Validate("abc"))
+	assert.True(t, scheme.IsPreRelease("v1.2.3-rc.0"))
+	assert.False(t, scheme.IsPreRelease("v1.2.3"))
+	assert.False(t, scheme.IsPreRelease("abc"))
+
+	nextVersion, isPre, err := scheme.Bump("v1.2.3", true)
+	assert.Nil(t, err)
+	assert.False(t, isPre)
+	assert.Equal(t, "v1.2.4", nextVersion)
+
+	nextVersion, isPre, err = scheme.Bump("1.2.3-rc.0", true)
+	assert.Nil(t, err)
+	assert.True(t, isPre)
+	assert.Equal(t, "1.2.3-rc.1", nextVersion)
+
+	branch, err := scheme.ReleaseBranch("v3.2.0-alpha.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "release-3.2", branch)
+	_, err = scheme.ReleaseBranch("abc")
+	assert.NotNil(t, err)
+}
+
+func TestSemVerIncrease(t *testing.T) {
+	tests := []struct {
+		name    string
+		version string
+		level   Level
+		want    string
+		wantErr bool
+	}{{
+		name:    "invalid version",
+		version: "abc",
+		want:    "abc",
+		wantErr: true,
+	}, {
+		name:    "patch",
+		version: "v1.2.0",
+		level:   LevelPatch,
+		want:    "v1.2.1",
+	}, {
+		name:    "minor",
+		version: "v1.2.3",
+		level:   LevelMinor,
+		want:    "v1.3.0",
+	}, {
+		name:    "major",
+		version: "1.2.3",
+		level:   LevelMajor,
+		want:    "2.0.0",
+	}, {
+		name:    "major before 1.0.0",
+		version: "v0.2.3",
+		level:   LevelMajor,
+		want:    "v0.3.0",
+	}, {
+		name:    "from a pre-release",
+		version: "v1.2.0-rc.1",
+		level:   LevelPatch,
+		want:    "v1.2.1",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewSemVer().Increase(tt.version, tt.level)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCalVer(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name        string
+		format      string
+		version     string
+		remainPre   bool
+		wantInvalid bool
+		wantPre     bool
+		wantVersion string
+		wantBumpErr bool
+	}{{
+		name:        "invalid version",
+		version:     "abc",
+		wantInvalid: true,
+		wantVersion: "abc",
+		wantBumpErr: true,
+	}, {
+		name:        "without zero padding",
+		version:     "2021.9.0",
+		wantInvalid: true,
+		wantVersion: "2021.9.0",
+		wantBumpErr: true,
+	}, {
+		name:        "same month",
+		version:     "2021.09.0",
+		wantVersion: "2021.09.1",
+	}, {
+		name:        "previous month",
+		version:     "v2021.08.3",
+		wantVersion: "v2021.09.0",
+	}, {
+		name:        "short year",
+		format:      "YY.0M.MICRO",
+		version:     "v21.04.1",
+		wantVersion: "v21.09.0",
+	}, {
+		name:        "week and day",
+		format:      "YYYY.WW.DD",
+		version:     "2021.30.2",
+		wantVersion: "2021.36.6",
+	}, {
+		name:        "same day without counters",
+		format:      "YYYY.0M.0D",
+		version:     "2021.09.06",
+		wantVersion: "2021.09.06",
+		wantBumpErr: true,
+	}, {
+		name:        "remain the pre-release",
+		version:     "2021.09.0-rc.1",
+		remainPre:   true,
+		wantPre:     true,
+		wantVersion: "2021.09.0-rc.2",
+	}, {
+		name:        "drop the pre-release",
+		version:     "2021.09.0-rc.1",
+		wantPre:     true,
+		wantVersion: "2021.09.0",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, err := newCalVer(tt.format, now)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.wantInvalid, scheme.Validate(tt.version) != nil)
+			assert.Equal(t, tt.wantPre, scheme.IsPreRelease(tt.version))
+
+			nextVersion, isPre, err := scheme.Bump(tt.version, tt.remainPre)
+			assert.Equal(t, tt.wantBumpErr, err != nil, err)
+			assert.Equal(t, tt.wantPre, isPre)
+			assert.Equal(t, tt.wantVersion, nextVersion)
+		})
+	}
+}
+
+func TestCalVerIncrease(t *testing.T) {
+	scheme, err := newCalVer("", func() time.Time {
+		return time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	})
+	assert.Nil(t, err)
+
+	nextVersion, err := scheme.Increase("v2021.08.3-rc.1", LevelMajor)
+	assert.Nil(t, err)
+	assert.Equal(t, "v2021.09.0", nextVersion)
+
+	nextVersion, err = scheme.Increase("2021.09.0", LevelPatch)
+	assert.Nil(t, err)
+	assert.Equal(t, "2021.09.1", nextVersion)
+
+	_, err = scheme.Increase("2021.9.0", LevelPatch)
+	assert.NotNil(t, err)
+}
+
+func TestCalVerReleaseBranch(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		version string
+		want    string
+		wantErr bool
+	}{{
+		name:    "invalid version",
+		version: "abc",
+		wantErr: true,
+	}, {
+		name:    "default format",
+		version: "v2021.09.3-rc.0",
+		want:    "release-2021.09",
+	}, {
+		name:    "short year",
+		format:  "YY.0M.MICRO",
+		version: "21.09.0",
+		want:    "release-21.09",
+	}, {
+		name:    "minor in the middle",
+		format:  "YYYY.MINOR.MICRO",
+		version: "2021.2.1",
+		want:    "release-2021.2",
+	}, {
+		name:    "without micro",
+		format:  "YYYY.0M.0D",
+		version: "2021.09.06",
+		want:    "release-2021.09.06",
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheme, err := NewCalVer(tt.format)
+			assert.Nil(t, err)
+
+			branch, err := scheme.ReleaseBranch(tt.version)
+			assert.Equal(t, tt.wantErr, err != nil, err)
+			assert.Equal(t, tt.want, branch)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	calVer, err := NewCalVer("")
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name    string
+		scheme  Scheme
+		version string
+		other   string
+		want    int
+		wantErr bool
+	}{{
+		name:    "semver",
+		scheme:  NewSemVer(),
+		version: "v1.10.0",
+		other:   "1.9.0",
+		want:    1,
+	}, {
+		name:    "semver pre-release",
+		scheme:  NewSemVer(),
+		version: "v1.0.0-rc.1",
+		other:   "v1.0.0",
+		want:    -1,
+	}, {
+		name:    "invalid semver",
+		scheme:  NewSemVer(),
+		version: "nightly",
+		other:   "v1.0.0",
+		wantErr: true,
+	}, {
+		name:    "calver",
+		scheme:  calVer,
+		version: "v2021.09.0",
+		other:   "v2021.10.0",
+		want:    -1,
+	}, {
+		name:    "calver micro",
+		scheme:  calVer,
+		version: "2021.09.10",
+		other:   "2021.09.9",
+		want:    1,
+	}, {
+		name:    "calver modifier",
+		scheme:  calVer,
+		version: "2021.09.0-rc.10",
+		other:   "2021.09.0-rc.9",
+		want:    1,
+	}, {
+		name:    "calver without modifier",
+		scheme:  calVer,
+		version: "2021.09.0-rc.0",
+		other:   "2021.09.0",
+		want:    -1,
+	}, {
+		name:    "equal",
+		scheme:  calVer,
+		version: "2021.09.0",
+		other:   "2021.09.0",
+	}, {
+		name:    "invalid calver",
+		scheme:  calVer,
+		version: "2021.09.0",
+		other:   "v1.2.3",
+		wantErr: true,
+	}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.scheme.Compare(tt.version, tt.other)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFirstCalVer(t *testing.T) {
+	now := func() time.Time {
+		return time.Date(2021, time.September, 6, 0, 0, 0, 0, time.UTC)
+	}
+	version, err := FirstCalVer("", now)
+	assert.Nil(t, err)
+	assert.Equal(t, "2021.09.0", version)
+
+	version, err = FirstCalVer("YY.MINOR.0D", now)
+	assert.Nil(t, err)
+	assert.Equal(t, "21.0.06", version)
+
+	_, err = FirstCalVer("MAJOR.MINOR", now)
+	assert.NotNil(t, err)
+}
@@ -17,14 +17,17 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
+	"strings"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -52,11 +55,17 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var maxConcurrency int
+	var providerHosts string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.IntVar(&maxConcurrency, "max-concurrency", 10,
+		"The upper limit of the repositories which can be released at the same time by a Releaser.")
+	flag.StringVar(&providerHosts, "provider-hosts", "",
+		"The ConfigMap (namespace/name) which maps the hostnames of the self-hosted git providers to the providers.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -78,9 +87,26 @@ func main() {
 		os.Exit(1)
 	}
 
+	if providerHosts != "" {
+		items := strings.SplitN(providerHosts, "/", 2)
+		if len(items) != 2 {
+			setupLog.Error(nil, "the provider hosts should be in the format of namespace/name", "value", providerHosts)
+			os.Exit(1)
+		}
+		// the cache of the manager is not started yet
+		if err = controllers.LoadProviderHosts(context.TODO(), mgr.GetAPIReader(), types.NamespacedName{
+			Namespace: items[0],
+			Name:      items[1],
+		}); err != nil {
+			setupLog.Error(err, "unable to load the provider hosts")
+			os.Exit(1)
+		}
+	}
+
 	if err = (&controllers.ReleaserReconciler{
-		Client:      mgr.GetClient(),
-		GitCacheDir: "tmp",
+		Client:         mgr.GetClient(),
+		GitCacheDir:    "tmp",
+		MaxConcurrency: maxConcurrency,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Releaser")
 		os.Exit(1)